@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type agentCmd struct {
+	ttl time.Duration
+}
+
+func (*agentCmd) Name() string {
+	return "agent"
+}
+func (*agentCmd) Synopsis() string {
+	return "keep the passphrase for other commands for a while"
+}
+func (*agentCmd) Usage() string {
+	return `agent [-ttl 1h]
+  Asks the passphrase of your GPG key once and hands it to baccounts
+  commands of yours until the time is up or it's stopped, like ssh-agent.
+  It listens on $BACCOUNTS_AGENT_SOCK, or in $XDG_RUNTIME_DIR.
+`
+}
+func (a *agentCmd) SetFlags(f *flag.FlagSet) {
+	f.DurationVar(&a.ttl, "ttl", time.Hour, "How long to keep the passphrase")
+}
+func (a *agentCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	// Other commands have to be able to save meanwhile
+	if b := (argv[0]).(*baccounts.Baccount); b != nil {
+		b.Unlock()
+	}
+
+	coder := baccounts.NewCoder()
+	pass, err := baccounts.ReadPassword("Passphrase of your GPG key:")
+	if err != nil {
+		return subcommands.ExitFailure
+	}
+	if err := coder.CheckPassphrase(pass); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	sock := baccounts.AgentSocket()
+	ln, err := baccounts.ListenSocket(sock)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		ln.Close()
+	}()
+
+	fmt.Printf("Agent listening on %s for %v\n", sock, a.ttl)
+	if err := baccounts.ServeAgent(ln, pass, a.ttl); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Agent stopped")
+	return subcommands.ExitSuccess
+}
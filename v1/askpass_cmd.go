@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+// askpassHelper is the name to link baccounts by for $SSH_ASKPASS and
+// $SUDO_ASKPASS, which can't give arguments.
+const askpassHelper = "baccounts-askpass"
+
+type askpassCmd struct{}
+
+func (*askpassCmd) Name() string {
+	return "askpass"
+}
+func (*askpassCmd) Synopsis() string {
+	return "print the password a prompt of ssh, sudo or git asks for"
+}
+func (*askpassCmd) Usage() string {
+	return `askpass prompt
+  Link baccounts as ` + askpassHelper + ` in $PATH and set $SSH_ASKPASS,
+  $SUDO_ASKPASS or $GIT_ASKPASS to it. The prompt is mapped to a site by the
+  "Askpass" rules of baccounts-config.json, then by the default ones:
+    {"Askpass": [{"Prompt": "^Enter VPN password", "Site": "vpn.work.com", "Profile": "work"}]}
+  The domain of the site has to match exactly, and so does the user the
+  prompt names, like me@localhost for sudo. Without a terminal, start
+  'baccounts agent' beforehand for the passphrase.
+`
+}
+func (*askpassCmd) SetFlags(f *flag.FlagSet) {
+}
+func (a *askpassCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	out := protocolStdout()
+	baccounts.ChooseSite = nil
+	prompt := strings.Join(f.Args(), " ")
+
+	config, err := baccounts.LoadConfig()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	rules := append(config.Askpass, baccounts.DefaultAskpassRules...)
+	_, site, err := b.AskpassSite(rules, prompt)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	pass, err := coder.Decode(site.EncodedPass)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintln(out, pass)
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type auditCmd struct {
+	maxDays  int
+	unknown  bool
+	minScore int
+	json     bool
+	breachDB string
+}
+
+func (*auditCmd) Name() string {
+	return "audit"
+}
+func (*auditCmd) Synopsis() string {
+	return "report reused, weak and old passwords"
+}
+func (*auditCmd) Usage() string {
+	return `audit [--max-days 365] [--unknown-age] [--min-score 3] [--breach-db path] [--json]
+  Passwords saved before baccounts recorded when they were changed have
+  no age; --unknown-age reports them as old too.
+`
+}
+func (a *auditCmd) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&a.maxDays, "max-days", 365, "Report passwords not changed for this many days; 0 for no limit")
+	f.BoolVar(&a.unknown, "unknown-age", false, "Report passwords of unknown age as old")
+	f.IntVar(&a.minScore, "min-score", baccounts.MinScore, "Report passwords with a lower strength score, 0 to 4")
+	f.BoolVar(&a.json, "json", false, "Print the report as JSON")
+	f.StringVar(&a.breachDB, "breach-db", "", "Have I Been Pwned SHA-1 dump, ordered by hash, to check passwords against")
+}
+func (a *auditCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	opts := baccounts.AuditOptions{
+		MinScore: a.minScore,
+		MaxAge:   time.Duration(a.maxDays) * 24 * time.Hour,
+		Unknown:  a.unknown,
+	}
+	if a.breachDB != "" {
+		db, err := baccounts.OpenBreachDB(a.breachDB)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		defer db.Close()
+		opts.Breaches = db
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	report, err := b.Audit(coder, opts)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if a.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tPROFILE\tSITE\tDETAIL")
+		for _, finding := range report.Findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", finding.Kind, finding.Profile, finding.Domain, finding.Detail)
+		}
+		w.Flush()
+		fmt.Printf("%d findings in %d sites\n", len(report.Findings), report.Sites)
+	}
+
+	if len(report.Findings) > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
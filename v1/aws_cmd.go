@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type awsCredentialsCmd struct {
+	name string
+}
+
+func (*awsCredentialsCmd) Name() string {
+	return "aws-credentials"
+}
+func (*awsCredentialsCmd) Synopsis() string {
+	return "print the AWS access key of the site for credential_process"
+}
+func (*awsCredentialsCmd) Usage() string {
+	return `aws-credentials [-name name] site
+  Prints the AWS access key kept in the fields ` + baccounts.AWSAccessKeyIdField + `,
+  ` + baccounts.AWSSecretAccessKeyField + ` and, for temporary keys, ` + baccounts.AWSSessionTokenField + `
+  and ` + baccounts.AWSExpirationField + ` (RFC 3339) of the site, as JSON for AWS SDKs.
+  Set them with field-set -secret, then in ~/.aws/config:
+    [profile work]
+    credential_process = baccounts aws-credentials aws.amazon.com
+  The site must match exactly.
+`
+}
+func (a *awsCredentialsCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&a.name, "name", "", "Profile name")
+}
+func (a *awsCredentialsCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	out := protocolStdout()
+	// SDKs read stdout only: no prompt to pick one of several sites
+	baccounts.ChooseSite = nil
+	if f.NArg() != 1 {
+		fmt.Println(a.Usage())
+		return subcommands.ExitUsageError
+	}
+
+	p, err := b.GetProfile(a.name)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	_, site, err := p.FindSiteExact(f.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	coder := baccounts.NewCoder()
+	if err := coder.SetCheckedPassphrase(); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	creds, err := site.AWSCredentials(coder, time.Now())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if err := creds.Write(out); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type restoreBackupCmd struct {
+	index int
+}
+
+func (*restoreBackupCmd) Name() string {
+	return "restore-backup"
+}
+func (*restoreBackupCmd) Synopsis() string {
+	return "list backups of the datafile or restore one of them"
+}
+func (*restoreBackupCmd) Usage() string {
+	return `restore-backup [--index n]
+`
+}
+func (r *restoreBackupCmd) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&r.index, "index", -1, "Index of the backup to restore, as listed without it")
+}
+func (r *restoreBackupCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	backups, err := baccounts.Backups(datafile)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if r.index < 0 {
+		for i, backup := range backups {
+			fmt.Printf("%d\t%s\n", i, backup)
+		}
+		return subcommands.ExitSuccess
+	}
+	if r.index >= len(backups) {
+		fmt.Printf("No backup #%d: %d backups found\n", r.index, len(backups))
+		return subcommands.ExitFailure
+	}
+
+	if err := b.RestoreBackup(datafile, backups[r.index]); err != nil {
+		fmt.Println("Failed to restore:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Restored", backups[r.index])
+	return subcommands.ExitSuccess
+}
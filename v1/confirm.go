@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+// confirmer asks by the command if given, else on the terminal; without
+// a terminal, or in the background of it, the answer is no.
+func confirmer(command []string) func(question string) bool {
+	return func(question string) bool {
+		if len(command) > 0 {
+			cmd := exec.Command(command[0], append(command[1:], question)...)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			return cmd.Run() == nil
+		}
+		tty, err := baccounts.OpenTTY()
+		if err != nil {
+			fmt.Printf("Denied without a Confirm command (%v): %s\n", err, question)
+			return false
+		}
+		defer tty.Close()
+		fmt.Fprintf(tty, "%s [y/N] ", question)
+		line, _ := bufio.NewReader(tty).ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// unlockUnattended sets the passphrase for commands that keep running
+// for others to use them: from the agent if it runs, else on the
+// terminal, unless there's none to ask on or it's not ours to read.
+func unlockUnattended(coder *baccounts.Coder) error {
+	if _, err := baccounts.AgentPassphrase(); err == nil {
+		return coder.SetCheckedPassphrase()
+	}
+	tty, err := baccounts.OpenTTY()
+	if err != nil {
+		return fmt.Errorf("No passphrase (%v): start 'baccounts agent' first", err)
+	}
+	tty.Close()
+	return coder.SetCheckedPassphrase()
+}
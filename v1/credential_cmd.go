@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+// protocolStdout points os.Stdout to stderr, for what the rest of the
+// code prints not to get mixed into a helper protocol, and returns the
+// real stdout for the protocol itself.
+func protocolStdout() *os.File {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	return out
+}
+
+type gitCredentialCmd struct {
+	profile string
+}
+
+func (*gitCredentialCmd) Name() string {
+	return "git-credential"
+}
+func (*gitCredentialCmd) Synopsis() string {
+	return "git credential helper: get, store or erase HTTPS credentials"
+}
+func (*gitCredentialCmd) Usage() string {
+	return `git-credential [-profile name] get|store|erase
+  Speaks the git credential helper protocol on stdin and stdout; set it up with
+    git config --global credential.helper "!baccounts git-credential"
+  as git runs "git credential-<helper>" for a helper that is neither an
+  absolute path nor starts with "!".
+  The host must match a site exactly. store only adds sites baccounts
+  doesn't know, and erase only deletes a site if it still has the
+  rejected password.
+`
+}
+func (g *gitCredentialCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&g.profile, "profile", "", "Profile to search, the default one if empty")
+}
+func (g *gitCredentialCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	out := protocolStdout()
+	// No prompt to pick one of several sites: stdin is git's
+	baccounts.ChooseSite = nil
+	if f.NArg() != 1 {
+		fmt.Println(g.Usage())
+		return subcommands.ExitUsageError
+	}
+
+	c, err := baccounts.ReadCredential(os.Stdin)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	p, err := b.GetProfile(g.profile)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	switch f.Arg(0) {
+	case "get":
+		err = g.get(p, c, out)
+	case "store":
+		err = g.store(b, datafile, p, c)
+	case "erase":
+		err = g.erase(b, datafile, p, c)
+	default:
+		// Unknown operations are to be ignored, for future ones of git
+		return subcommands.ExitSuccess
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// get answers with the username and password of the site, and with
+// nothing if there's none, for git to try other helpers or to ask.
+func (g *gitCredentialCmd) get(p *baccounts.Profile, c baccounts.Credential, out *os.File) error {
+	_, site, err := p.FindCredential(c)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	pass, err := coder.Decode(site.EncodedPass)
+	if err != nil {
+		return err
+	}
+	username := c["username"]
+	if username == "" {
+		username = site.Username()
+	}
+	return baccounts.Credential{"username": username, "password": pass}.Write(out)
+}
+
+func (g *gitCredentialCmd) store(b *baccounts.Baccount, datafile string, p *baccounts.Profile, c baccounts.Credential) error {
+	if _, _, err := p.FindCredential(c); err == nil {
+		return nil
+	}
+	if c["password"] == "" {
+		return fmt.Errorf("No password to store")
+	}
+
+	coder, err := b.Coder()
+	if err != nil {
+		return err
+	}
+	encpass, err := coder.Encode(c["password"], 0)
+	if err != nil {
+		return err
+	}
+	mail := ""
+	if strings.Contains(c["username"], "@") {
+		mail = c["username"]
+	}
+	u := baccounts.Credential{"protocol": c["protocol"], "host": c["host"]}.URL()
+	if err := p.AddSite(c["host"], u.String(), c["username"], encpass, mail); err != nil {
+		return err
+	}
+	b.SetMessage("Store git credential for %s @ %s", c["host"], p.Name)
+	return b.UpdateConfigFile(datafile)
+}
+
+func (g *gitCredentialCmd) erase(b *baccounts.Baccount, datafile string, p *baccounts.Profile, c baccounts.Credential) error {
+	key, site, err := p.FindCredential(c)
+	if err != nil {
+		return nil
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	pass, err := coder.Decode(site.EncodedPass)
+	if err != nil {
+		return err
+	}
+	if pass != c["password"] {
+		fmt.Println("Password of", key, "has changed: not erased")
+		return nil
+	}
+	if err := p.DeleteSite(key); err != nil {
+		return err
+	}
+	b.SetMessage("Erase git credential for %s @ %s", key, p.Name)
+	return b.UpdateConfigFile(datafile)
+}
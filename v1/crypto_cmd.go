@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"strings"
 
 	"github.com/google/subcommands"
 	"github.com/kuenishi/baccounts/pkg"
@@ -117,7 +118,7 @@ func (g *exportCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interfac
 		}
 		profiles = append(profiles, profile)
 	}
-	c := &baccounts.Baccount{profiles, b.DefaultMail, b.Version, false}
+	c := &baccounts.Baccount{Profiles: profiles, DefaultMail: b.DefaultMail, Version: b.Version}
 
 	if err := c.UpdateConfigFile(g.file); err != nil {
 		fmt.Println("Failed to save to", g.file)
@@ -125,3 +126,55 @@ func (g *exportCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interfac
 	}
 	return subcommands.ExitSuccess
 }
+
+type recipientsCmd struct {
+	set string
+}
+
+func (*recipientsCmd) Name() string {
+	return "recipients"
+}
+func (*recipientsCmd) Synopsis() string {
+	return "show or set the keys new secrets are encrypted to"
+}
+func (*recipientsCmd) Usage() string {
+	return `recipients [--set mail,mail...]
+`
+}
+func (r *recipientsCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&r.set, "set", "", "Comma-separated mail addresses or key IDs; \"-\" for all public keys")
+}
+func (r *recipientsCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	if r.set == "" {
+		if len(b.Recipients) == 0 {
+			fmt.Println("All public keys in the keyring")
+		}
+		for _, recipient := range b.Recipients {
+			fmt.Println(recipient)
+		}
+		return subcommands.ExitSuccess
+	}
+
+	recipients := []string{}
+	if r.set != "-" {
+		recipients = strings.Split(r.set, ",")
+	}
+	coder := baccounts.NewCoder()
+	if err := coder.SetRecipients(recipients); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	b.Recipients = recipients
+	b.SetMessage("Set recipients to %s", r.set)
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Secrets saved from now on are encrypted to:", r.set)
+	fmt.Println("Existing secrets keep their keys until updated.")
+	return subcommands.ExitSuccess
+}
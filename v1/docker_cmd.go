@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+// dockerHelper is the name docker runs a credential helper by, for a
+// "credsStore": "baccounts" in ~/.docker/config.json.
+const dockerHelper = "docker-credential-baccounts"
+
+type dockerCredentialCmd struct {
+	profile string
+}
+
+func (*dockerCredentialCmd) Name() string {
+	return "docker-credential"
+}
+func (*dockerCredentialCmd) Synopsis() string {
+	return "docker credential helper: store, get, erase or list registry logins"
+}
+func (*dockerCredentialCmd) Usage() string {
+	return `docker-credential [-profile name] store|get|erase|list
+  Speaks the docker credential helper protocol on stdin and stdout. Link
+  baccounts as ` + dockerHelper + ` in $PATH and set "credsStore":
+  "baccounts" in ~/.docker/config.json to use it; the profile then comes
+  from $BACCOUNTS_DOCKER_PROFILE. Registry logins are sites tagged "docker".
+`
+}
+func (d *dockerCredentialCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&d.profile, "profile", os.Getenv("BACCOUNTS_DOCKER_PROFILE"), "Profile of the registry logins, the default one if empty")
+}
+func (d *dockerCredentialCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	out := protocolStdout()
+	baccounts.ChooseSite = nil
+	if f.NArg() != 1 {
+		fmt.Println(d.Usage())
+		return subcommands.ExitUsageError
+	}
+
+	// Docker shows what a failing helper writes to stdout
+	if err := d.run(b, datafile, f.Arg(0), out); err != nil {
+		fmt.Fprintln(out, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+func (d *dockerCredentialCmd) run(b *baccounts.Baccount, datafile, op string, out io.Writer) error {
+	p, err := b.GetProfile(d.profile)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "store":
+		var c baccounts.RegistryCredential
+		if err := json.NewDecoder(os.Stdin).Decode(&c); err != nil {
+			return err
+		}
+		coder, err := b.Coder()
+		if err != nil {
+			return err
+		}
+		if err := p.StoreRegistry(coder, &c); err != nil {
+			return err
+		}
+		b.SetMessage("Store registry login for %s @ %s", c.ServerURL, p.Name)
+		return b.UpdateConfigFile(datafile)
+
+	case "get":
+		serverURL, err := readServerURL()
+		if err != nil {
+			return err
+		}
+		_, site, err := p.FindRegistry(serverURL)
+		if err != nil {
+			return err
+		}
+		coder := baccounts.NewCoder()
+		coder.SetPassphrase()
+		secret, err := coder.Decode(site.EncodedPass)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(&baccounts.RegistryCredential{ServerURL: serverURL, Username: site.Name, Secret: secret})
+
+	case "erase":
+		serverURL, err := readServerURL()
+		if err != nil {
+			return err
+		}
+		key, _, err := p.FindRegistry(serverURL)
+		if err != nil {
+			return err
+		}
+		if err := p.DeleteSite(key); err != nil {
+			return err
+		}
+		b.SetMessage("Erase registry login for %s @ %s", serverURL, p.Name)
+		return b.UpdateConfigFile(datafile)
+
+	case "list":
+		return json.NewEncoder(out).Encode(p.Registries())
+	}
+	return fmt.Errorf("Unknown operation: %s", op)
+}
+
+// readServerURL reads the registry get and erase are asked about.
+func readServerURL() (string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	serverURL := strings.TrimSpace(string(data))
+	if serverURL == "" {
+		return "", fmt.Errorf("No server URL given")
+	}
+	return serverURL, nil
+}
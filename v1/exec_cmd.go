@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+// listFlag collects the values of a flag given several times.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type execCmd struct {
+	name string
+	env  listFlag
+}
+
+func (*execCmd) Name() string {
+	return "exec"
+}
+func (*execCmd) Synopsis() string {
+	return "run a command with secrets in its environment"
+}
+func (*execCmd) Usage() string {
+	return `exec [-name name] -env NAME=site:host [-env NAME=field:host#field ...] -- command [args...]
+  Secrets are decrypted with one passphrase prompt and only given to the
+  command, in its environment. Its exit code is passed through.
+`
+}
+func (e *execCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&e.name, "name", "", "Profile name")
+	f.Var(&e.env, "env", "NAME=site:host or NAME=field:host#field to set, may be repeated")
+}
+func (e *execCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	if f.NArg() == 0 {
+		fmt.Println("No command given")
+		return subcommands.ExitUsageError
+	}
+	names := make([]string, 0, len(e.env))
+	refs := make([]*baccounts.SecretRef, 0, len(e.env))
+	for _, env := range e.env {
+		name, ref, ok := strings.Cut(env, "=")
+		if !ok || name == "" {
+			fmt.Println("Invalid -env, give NAME=site:host:", env)
+			return subcommands.ExitUsageError
+		}
+		r, err := baccounts.ParseSecretRef(ref)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitUsageError
+		}
+		names = append(names, name)
+		refs = append(refs, r)
+	}
+
+	p, err := b.GetProfile(e.name)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	environ := os.Environ()
+	if len(refs) > 0 {
+		coder := baccounts.NewCoder()
+		coder.SetPassphrase()
+		for i, r := range refs {
+			secret, err := p.Secret(coder, r)
+			if err != nil {
+				fmt.Printf("Error on %s: %v\n", r, err)
+				return subcommands.ExitFailure
+			}
+			environ = append(environ, names[i]+"="+secret)
+		}
+	}
+	// The command may well run baccounts itself
+	b.Unlock()
+
+	return subcommands.ExitStatus(run(f.Args(), environ))
+}
+
+// run runs a command to its end, handing signals over to it, and returns
+// its exit code; 128+n if signal n killed it, like shells do.
+func run(args, environ []string) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = environ
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Println("Error:", err)
+		return 127
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	} else if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type fieldSetCmd struct {
+	site   string
+	name   string
+	field  string
+	value  string
+	secret bool
+	delete bool
+}
+
+func (*fieldSetCmd) Name() string {
+	return "field-set"
+}
+func (*fieldSetCmd) Synopsis() string {
+	return "set or delete a named field of the site"
+}
+func (*fieldSetCmd) Usage() string {
+	return `field-set -site site -field field [-name name] [-secret] [-value value] [-delete]
+  Without -value, the value is asked for. A secret value is always asked
+  for, to keep it out of ps and the shell history.
+`
+}
+func (c *fieldSetCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.site, "site", "", "Site of the field (required)")
+	f.StringVar(&c.name, "name", "", "Profile name")
+	f.StringVar(&c.field, "field", "", "Field name (required)")
+	f.StringVar(&c.value, "value", "", "Value, instead of asking for it (not with -secret)")
+	f.BoolVar(&c.secret, "secret", false, "Encrypt the value")
+	f.BoolVar(&c.delete, "delete", false, "Delete the field")
+}
+func (c *fieldSetCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	if c.secret && c.value != "" {
+		fmt.Println("Error: -value can't be given with -secret, type the value in when asked")
+		return subcommands.ExitUsageError
+	}
+	p, site, err := findSite(b, c.name, c.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if c.delete {
+		err = site.DeleteField(c.field)
+		b.SetMessage("Delete field %s of %s @ %s", c.field, c.site, p.Name)
+	} else {
+		value := c.value
+		if value == "" && c.secret {
+			value, err = baccounts.ReadPassword(c.field + ": ")
+		} else if value == "" {
+			value, err = readLine(c.field + ": ")
+		}
+		if err != nil {
+			return subcommands.ExitFailure
+		}
+
+		coder, e := b.Coder()
+		if e != nil {
+			fmt.Println("Error:", e)
+			return subcommands.ExitFailure
+		}
+		err = site.SetField(coder, c.field, value, c.secret)
+		b.SetMessage("Set field %s of %s @ %s", c.field, c.site, p.Name)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type fieldGetCmd struct {
+	site  string
+	name  string
+	field string
+	print bool
+}
+
+func (*fieldGetCmd) Name() string {
+	return "field-get"
+}
+func (*fieldGetCmd) Synopsis() string {
+	return "print a plain field of the site, or copy a secret one to clipboard"
+}
+func (*fieldGetCmd) Usage() string {
+	return `field-get -site site -field field [-name name] [-print]
+`
+}
+func (c *fieldGetCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.site, "site", "", "Site of the field (required)")
+	f.StringVar(&c.name, "name", "", "Profile name")
+	f.StringVar(&c.field, "field", "", "Field name (required)")
+	f.BoolVar(&c.print, "print", false, "Print secret fields too instead of copying them")
+}
+func (c *fieldGetCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	_, site, err := findSite(b, c.name, c.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	field, ok := site.Fields[c.field]
+	if !ok {
+		fmt.Printf("No field %s in %s\n", c.field, site.Url)
+		return subcommands.ExitFailure
+	}
+	if !field.Secret || c.print {
+		coder := baccounts.NewCoder()
+		if field.Secret {
+			coder.SetPassphrase()
+		}
+		value, err := site.GetField(coder, c.field)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		fmt.Println(value)
+		return subcommands.ExitSuccess
+	}
+	return b.ShowField(site, c.field)
+}
+
+type fieldListCmd struct {
+	site string
+	name string
+}
+
+func (*fieldListCmd) Name() string {
+	return "field-list"
+}
+func (*fieldListCmd) Synopsis() string {
+	return "list fields of the site"
+}
+func (*fieldListCmd) Usage() string {
+	return `field-list -site site [-name name]
+`
+}
+func (c *fieldListCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.site, "site", "", "Site of the fields (required)")
+	f.StringVar(&c.name, "name", "", "Profile name")
+}
+func (c *fieldListCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	_, site, err := findSite(b, c.name, c.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	for _, name := range site.FieldNames() {
+		if field := site.Fields[name]; field.Secret {
+			fmt.Printf("%s:\t(secret)\n", name)
+		} else {
+			fmt.Printf("%s:\t%s\n", name, field.Value)
+		}
+	}
+	if site.EncodedNote != "" {
+		fmt.Println("(has a note)")
+	}
+	return subcommands.ExitSuccess
+}
+
+type noteCmd struct {
+	site string
+	name string
+	set  bool
+}
+
+func (*noteCmd) Name() string {
+	return "note"
+}
+func (*noteCmd) Synopsis() string {
+	return "print the note of the site, or set it from stdin"
+}
+func (*noteCmd) Usage() string {
+	return `note -site site [-name name] [-set < note.txt]
+`
+}
+func (c *noteCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.site, "site", "", "Site of the note (required)")
+	f.StringVar(&c.name, "name", "", "Profile name")
+	f.BoolVar(&c.set, "set", false, "Replace the note with stdin; empty input removes it")
+}
+func (c *noteCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	p, site, err := findSite(b, c.name, c.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if !c.set {
+		coder := baccounts.NewCoder()
+		coder.SetPassphrase()
+		note, err := site.Note(coder)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		fmt.Print(note)
+		return subcommands.ExitSuccess
+	}
+
+	note, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	coder, err := b.Coder()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if err := site.SetNote(coder, string(note)); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	b.SetMessage("Set note of %s @ %s", c.site, p.Name)
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
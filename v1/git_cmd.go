@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type gitInitCmd struct {
+	remote string
+}
+
+func (*gitInitCmd) Name() string {
+	return "git-init"
+}
+func (*gitInitCmd) Synopsis() string {
+	return "track the datafile in git, committing every change"
+}
+func (*gitInitCmd) Usage() string {
+	return `git-init [--remote url]
+  The directory of the datafile becomes the repository, so it must hold
+  nothing but the datafile.
+`
+}
+func (g *gitInitCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&g.remote, "remote", "", "URL of the remote repository to sync with")
+}
+func (g *gitInitCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	if err := b.GitInit(datafile, g.remote); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Tracking", datafile, "in git")
+	return subcommands.ExitSuccess
+}
+
+type syncCmd struct {
+	remote  string
+	resolve string
+}
+
+func (*syncCmd) Name() string {
+	return "sync"
+}
+func (*syncCmd) Synopsis() string {
+	return "pull, merge and push the datafile with the git remote"
+}
+func (*syncCmd) Usage() string {
+	return `sync [--remote origin] [--resolve ask|ours|theirs]
+`
+}
+func (s *syncCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.remote, "remote", "origin", "Name of the git remote")
+	f.StringVar(&s.resolve, "resolve", "ask", "How to resolve conflicts timestamps can't: ask, ours or theirs")
+}
+func (s *syncCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	resolve, err := resolverFor(s.resolve)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitUsageError
+	}
+
+	summary, err := b.Sync(datafile, s.remote, resolve)
+	if summary != nil {
+		summary.Print()
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine prompts and reads one line from stdin, without the newline.
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+type initCmd struct {
+	mail      string
+	recipient string
+	profile   string
+}
+
+func (*initCmd) Name() string {
+	return "init"
+}
+func (*initCmd) Synopsis() string {
+	return "create a new datafile"
+}
+func (*initCmd) Usage() string {
+	return `init [--mail mail] [--recipient key] [--profile name]
+`
+}
+func (i *initCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&i.mail, "mail", "", "Default mail address")
+	f.StringVar(&i.recipient, "recipient", "", "Mail address or ID of the GPG key to encrypt to (default: mail)")
+	f.StringVar(&i.profile, "profile", "", "Name of the default profile (default: mail)")
+}
+func (i *initCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	if b != nil {
+		fmt.Println("Datafile already exists:", datafile)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Creating a new datafile:", datafile)
+
+	var err error
+	for i.mail == "" {
+		if i.mail, err = readLine("Default mail: "); err != nil {
+			return subcommands.ExitFailure
+		}
+	}
+	if i.recipient == "" {
+		if i.recipient, err = readLine(fmt.Sprintf("Recipient key [%s]: ", i.mail)); err != nil {
+			return subcommands.ExitFailure
+		}
+		if i.recipient == "" {
+			i.recipient = i.mail
+		}
+	}
+	if i.profile == "" {
+		i.profile = i.mail
+	}
+
+	coder := baccounts.NewCoder()
+	if coder.FindKey(i.recipient) == nil {
+		fmt.Printf("No key for %s in %s: see list-keys\n", i.recipient, coder.PublicKeyringFile())
+		return subcommands.ExitFailure
+	}
+
+	if _, err := baccounts.Init(datafile, i.mail, []string{i.recipient}, i.profile); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Created", datafile, "with default profile", i.profile)
+	return subcommands.ExitSuccess
+}
@@ -2,20 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"crypto/rand"
-	"math/big"
 	"net/url"
+	"strconv"
 
 	"github.com/google/subcommands"
 	baccounts "github.com/kuenishi/baccounts/pkg"
 )
 
 type listCmd struct {
+	tag    string
+	folder string
+	tree   bool
 }
 
 func (*listCmd) Name() string {
@@ -25,13 +31,17 @@ func (*listCmd) Synopsis() string {
 	return "List all profiles"
 }
 func (*listCmd) Usage() string {
-	return `list`
+	return `list [-tag tag] [-folder folder] [-tree]
+`
 }
 func (l *listCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&l.tag, "tag", "", "Only sites with this tag")
+	f.StringVar(&l.folder, "folder", "", "Only sites in this folder")
+	f.BoolVar(&l.tree, "tree", false, "Show as a tree of folders and profiles")
 }
 func (l *listCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
 	var b = (argv[0]).(*baccounts.Baccount)
-	if err := b.List(); err != nil {
+	if err := b.ListSites(baccounts.ListOptions{Tag: l.tag, Folder: l.folder, Tree: l.tree}); err != nil {
 		return subcommands.ExitFailure
 	}
 	return subcommands.ExitSuccess
@@ -58,7 +68,7 @@ func (a *addProfileCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...inte
 	var b = (argv[0]).(*baccounts.Baccount)
 	var datafile = (argv[1]).(string)
 	if err := b.AddProfile(a.name, datafile); err != nil {
-		slog.Info("fail", err)
+		slog.Info("fail", "err", err)
 		return subcommands.ExitFailure
 	}
 	return subcommands.ExitSuccess
@@ -96,11 +106,12 @@ func (g *updateCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interfac
 }
 
 type generateCmd struct {
-	url  string
-	name string
-	mail string
-	len  int
-	num  bool
+	url      string
+	name     string
+	mail     string
+	len      int
+	num      bool
+	breachDB string
 }
 
 func (*generateCmd) Name() string {
@@ -110,15 +121,16 @@ func (*generateCmd) Synopsis() string {
 	return "Generates and save password for the site"
 }
 func (*generateCmd) Usage() string {
-	return `generate --url url --name name --mail mail --len 16
+	return `generate --url url --name name --mail mail --len 16 [--breach-db path]
 `
 }
 func (g *generateCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&g.url, "url", "https://example.com", "URL of the site")
 	f.StringVar(&g.name, "name", "", "Profile of the site")
 	f.StringVar(&g.mail, "mail", "", "Mail address")
-	f.IntVar(&g.len, "len", 16, "Length of the pass")
+	f.IntVar(&g.len, "len", baccounts.DefaultPassLength, "Length of the pass")
 	f.BoolVar(&g.num, "num", false, "Num-only")
+	f.StringVar(&g.breachDB, "breach-db", "", "Have I Been Pwned SHA-1 dump to reject breached passwords with")
 }
 func (g *generateCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
 	fmt.Printf("Generate profiles: %s @ %s\n", g.name, g.url)
@@ -130,9 +142,6 @@ func (g *generateCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interf
 		return subcommands.ExitFailure
 	}
 
-	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-	var nums = []rune("0123456789")
-
 	p, e := b.GetProfile(g.name)
 	if e != nil {
 		fmt.Println("Error:", e)
@@ -146,30 +155,42 @@ func (g *generateCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interf
 	}
 	fmt.Println("host:", u.Host)
 
-	bytes := make([]rune, g.len)
-	for i := 0; i < g.len; i++ {
-		if g.num {
-			j, e := rand.Int(rand.Reader, big.NewInt(int64(len(nums))))
-			if e != nil {
-				fmt.Println("Error:", e)
-				return subcommands.ExitFailure
-			}
-			bytes[i] = nums[j.Int64()]
-		} else {
-			j, e := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
-			if e != nil {
-				fmt.Println("Error:", e)
-				return subcommands.ExitFailure
-			}
-			bytes[i] = letters[j.Int64()]
+	pass, e := baccounts.GeneratePassword(g.len, g.num)
+	if e != nil {
+		fmt.Println("Error:", e)
+		return subcommands.ExitFailure
+	}
+	if strength := baccounts.EstimateStrength(pass); strength.Score < baccounts.MinScore {
+		fmt.Printf("Warning: generated password is %s: %s (try a longer --len, or without --num)\n", strength, strings.Join(strength.Reasons, ", "))
+	}
+
+	if g.breachDB != "" {
+		db, e := baccounts.OpenBreachDB(g.breachDB)
+		if e != nil {
+			fmt.Println("Error:", e)
+			return subcommands.ExitFailure
+		}
+		n, e := db.Count(pass)
+		db.Close()
+		if e != nil {
+			fmt.Println("Error:", e)
+			return subcommands.ExitFailure
+		}
+		if n > 0 {
+			fmt.Printf("Generated password was seen %d times in breaches: try again, maybe longer\n", n)
+			return subcommands.ExitFailure
 		}
 	}
 
-	fmt.Println(string(bytes))
+	fmt.Println(pass)
 
-	coder := baccounts.NewCoder()
+	coder, err := b.Coder()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
 	// TODO: check we already have same site
-	encpass, err := coder.Encode(string(bytes), 0)
+	encpass, err := coder.Encode(pass, 0)
 
 	if err != nil {
 		fmt.Println("Can't decode pass:", err)
@@ -182,16 +203,19 @@ func (g *generateCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interf
 		return subcommands.ExitFailure
 	}
 
+	b.SetMessage("Generate password for %s @ %s", u.Host, p.Name)
 	if err := b.UpdateConfigFile(datafile); err != nil {
-		slog.Info("Failed to save", "datafile", datafile, err)
+		slog.Info("Failed to save", "datafile", datafile, "err", err)
 		return subcommands.ExitFailure
 	}
 	return subcommands.ExitSuccess
 }
 
 type showCmd struct {
-	name string
-	site string
+	name  string
+	site  string
+	info  bool
+	field string
 }
 
 func (*showCmd) Name() string {
@@ -201,12 +225,14 @@ func (*showCmd) Synopsis() string {
 	return "Show password for the site"
 }
 func (*showCmd) Usage() string {
-	return `show -site example.com -mail mail -name name
+	return `show -site example.com -mail mail -name name [-info] [-field field]
 `
 }
 func (g *showCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&g.site, "site", "example.com", "Site name of the acc")
 	f.StringVar(&g.name, "name", "", "Profile")
+	f.BoolVar(&g.info, "info", false, "Show details and password strength instead of copying it")
+	f.StringVar(&g.field, "field", "", "Copy this field instead of the password")
 }
 
 func (g *showCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
@@ -228,6 +254,12 @@ func (g *showCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{
 	if err != nil {
 		return subcommands.ExitFailure
 	}
+	if g.info {
+		return b.Info(site)
+	}
+	if g.field != "" {
+		return b.ShowField(site, g.field)
+	}
 	return b.Show(site)
 }
 
@@ -263,24 +295,101 @@ func (g *setDefaultCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...inte
 	}
 
 	p.SetDefault(true)
+	b.SetMessage("Set default profile %s", p.Name)
 	b.UpdateConfigFile(datafile)
 
 	fmt.Println("Set default:", p.Name, p.Default)
 	return subcommands.ExitSuccess
 }
 
-func main() {
-	b, datafile, err := baccounts.LoadAccounts()
+// findSite looks a site up in the named profile, or the default one.
+func findSite(b *baccounts.Baccount, name, site string) (*baccounts.Profile, *baccounts.Site, error) {
+	p, err := b.GetProfile(name)
 	if err != nil {
-		fmt.Printf("baccounts version %s - data file version: %s\n", baccounts.Version, b.Version)
-		os.Exit(1)
+		return nil, nil, err
+	}
+	s, err := p.FindSite(site)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, s, nil
+}
+
+// chooseSite asks which one to use when several sites match.
+func chooseSite(p *baccounts.Profile, keys []string) (int, error) {
+	for i, key := range keys {
+		site := p.Sites[key]
+		fmt.Printf("%d\t%s\t%s\t%s\n", i, key, site.Url, site.Mail)
 	}
+	for {
+		line, err := readLine("Which one? ")
+		if err != nil {
+			return 0, err
+		}
+		if i, err := strconv.Atoi(line); err == nil && i >= 0 && i < len(keys) {
+			return i, nil
+		}
+	}
+}
+
+func loadAccounts(file, vault string) (*baccounts.Baccount, string, error) {
+	datafile, err := baccounts.Datafile(file, vault)
+	if err != nil {
+		return nil, "", err
+	}
+	if datafile != "" {
+		b, err := baccounts.LoadAccountsFrom(datafile)
+		return b, datafile, err
+	}
+	b, datafile, err := baccounts.LoadAccounts()
+	if errors.Is(err, fs.ErrNotExist) {
+		// Found nowhere: init creates a new one at the new place
+		if dflt, e := baccounts.DefaultDatafile(); e == nil {
+			datafile = dflt
+		}
+	}
+	return b, datafile, err
+}
+
+// Commands that work without a datafile
+var noDatafile = map[string]bool{
+	"":          true,
+	"help":      true,
+	"flags":     true,
+	"commands":  true,
+	"test":      true,
+	"list-keys": true,
+	"init":      true,
+	"agent":     true,
+}
+
+// helpers are names baccounts can be run by, e.g. through a symlink, for
+// tools that run a program without arguments, with the command to run.
+var helpers = map[string]string{
+	dockerHelper:  "docker-credential",
+	askpassHelper: "askpass",
+	nativeHelper:  "native-host",
+}
+
+func helperArgs(args []string) []string {
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	if cmd, ok := helpers[name]; ok {
+		return append([]string{args[0], cmd}, args[1:]...)
+	}
+	return args
+}
+
+func main() {
+	var file, vault string
+	flag.StringVar(&file, "file", "", "Datafile to use, instead of $BACCOUNTS_FILE or the default")
+	flag.StringVar(&vault, "vault", "", "Name of the vault in baccounts-config.json to use")
 
 	subcommands.Register(subcommands.HelpCommand(), "meta")
 	subcommands.Register(subcommands.FlagsCommand(), "meta")
 	subcommands.Register(subcommands.CommandsCommand(), "meta")
 	subcommands.Register(&testCmd{}, "meta")
 	subcommands.Register(&listKeysCmd{}, "meta")
+	subcommands.Register(&initCmd{}, "meta")
 
 	// profiles
 	subcommands.Register(&listCmd{}, "profile")
@@ -293,9 +402,50 @@ func main() {
 	// delete deletes site info
 	subcommands.Register(&showCmd{}, "profile")
 	subcommands.Register(&setDefaultCmd{}, "profile")
+	subcommands.Register(&recipientsCmd{}, "profile")
+	subcommands.Register(&auditCmd{}, "profile")
+	subcommands.Register(&otpSetCmd{}, "otp")
+	subcommands.Register(&otpCmd{}, "otp")
+	subcommands.Register(&fieldSetCmd{}, "field")
+	subcommands.Register(&fieldGetCmd{}, "field")
+	subcommands.Register(&fieldListCmd{}, "field")
+	subcommands.Register(&noteCmd{}, "field")
+	subcommands.Register(&execCmd{}, "field")
+	subcommands.Register(&renderCmd{}, "field")
+	subcommands.Register(&netrcCmd{}, "field")
+	subcommands.Register(&awsCredentialsCmd{}, "field")
+	subcommands.Register(&tagCmd{}, "organize")
+	subcommands.Register(&folderCmd{}, "organize")
+	subcommands.Register(&searchCmd{}, "organize")
+	subcommands.Register(&uiCmd{}, "organize")
 	subcommands.Register(&exportCmd{}, "compat")
-
+	subcommands.Register(&mergeCmd{}, "compat")
+	subcommands.Register(&restoreBackupCmd{}, "compat")
+	subcommands.Register(&gitInitCmd{}, "sync")
+	subcommands.Register(&syncCmd{}, "sync")
+	subcommands.Register(&gitCredentialCmd{}, "sync")
+	subcommands.Register(&dockerCredentialCmd{}, "sync")
+	subcommands.Register(&askpassCmd{}, "sync")
+	subcommands.Register(&agentCmd{}, "sync")
+	subcommands.Register(&serveCmd{}, "sync")
+	subcommands.Register(&nativeHostCmd{}, "sync")
+	subcommands.Register(&sshKeyCmd{}, "sync")
+	subcommands.Register(&sshAgentCmd{}, "sync")
+
+	os.Args = helperArgs(os.Args)
 	flag.Parse()
+	b, datafile, err := loadAccounts(file, vault)
+	if errors.Is(err, fs.ErrNotExist) {
+		if !noDatafile[flag.Arg(0)] {
+			fmt.Printf("No datafile found at %s: create one with 'baccounts init'\n", datafile)
+			os.Exit(1)
+		}
+	} else if err != nil {
+		fmt.Printf("baccounts version %s: %v\n", baccounts.Version, err)
+		os.Exit(1)
+	}
+
+	baccounts.ChooseSite = chooseSite
 	ctx := context.Background()
 	ret := int(subcommands.Execute(ctx, b, datafile))
 
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type mergeCmd struct {
+	resolve string
+}
+
+func (*mergeCmd) Name() string {
+	return "merge"
+}
+func (*mergeCmd) Synopsis() string {
+	return "merge another datafile into yours"
+}
+func (*mergeCmd) Usage() string {
+	return `merge [--resolve ask|ours|theirs] other.json
+`
+}
+func (m *mergeCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&m.resolve, "resolve", "ask", "How to resolve conflicts timestamps can't: ask, ours or theirs")
+}
+func (m *mergeCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	if f.NArg() != 1 {
+		fmt.Println("Usage:", m.Usage())
+		return subcommands.ExitUsageError
+	}
+
+	other, err := baccounts.LoadKeys(f.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	resolve, err := resolverFor(m.resolve)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitUsageError
+	}
+
+	summary, err := b.Merge(other, resolve)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	summary.Print()
+	if !summary.Changed() {
+		return subcommands.ExitSuccess
+	}
+
+	b.SetMessage("Merge %s", f.Arg(0))
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+func resolverFor(name string) (baccounts.Resolver, error) {
+	switch name {
+	case "ask":
+		return askConflict, nil
+	case "ours":
+		return func(*baccounts.Conflict) (bool, error) { return false, nil }, nil
+	case "theirs":
+		return func(*baccounts.Conflict) (bool, error) { return true, nil }, nil
+	}
+	return nil, fmt.Errorf("Unknown --resolve: %s", name)
+}
+
+func askConflict(c *baccounts.Conflict) (bool, error) {
+	fmt.Printf("Conflict on %s @ %s\n", c.Domain, c.Profile)
+	fmt.Printf("  ours:   %s\t%s\t%v\n", c.Ours.Url, c.Ours.Mail, c.Ours.Modified)
+	fmt.Printf("  theirs: %s\t%s\t%v\n", c.Theirs.Url, c.Theirs.Mail, c.Theirs.Modified)
+
+	for {
+		line, err := readLine("Keep [o]urs or take [t]heirs? ")
+		if err != nil {
+			return false, err
+		}
+		switch line {
+		case "o", "ours":
+			return false, nil
+		case "t", "theirs":
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+// nativeHelper is the name to link baccounts by for the "path" of the
+// native messaging manifest, which can't give arguments.
+const nativeHelper = "baccounts-native-host"
+
+type nativeHostCmd struct{}
+
+func (*nativeHostCmd) Name() string {
+	return "native-host"
+}
+func (*nativeHostCmd) Synopsis() string {
+	return "browser native messaging host giving logins to an extension"
+}
+func (*nativeHostCmd) Usage() string {
+	return `native-host
+  Answers the browser extension with logins of sites whose URL has the
+  host of the page. Link baccounts as ` + nativeHelper + ` and give its
+  full path in the native messaging manifest of the browser:
+    {"name": "baccounts", "type": "stdio", "path": "/usr/local/bin/` + nativeHelper + `", ...}
+  Every answer with passwords has to be confirmed, by the "Confirm" command
+  of baccounts-config.json or else on the terminal; without either, all
+  are denied. The passphrase comes from 'baccounts agent'.
+`
+}
+func (*nativeHostCmd) SetFlags(f *flag.FlagSet) {
+}
+func (n *nativeHostCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	out := protocolStdout()
+	// The browser keeps the host running: don't keep others from saving
+	b.Unlock()
+	baccounts.ChooseSite = nil
+
+	config, err := baccounts.LoadConfig()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	h := baccounts.NewNativeHost(b, baccounts.NewCoder())
+	h.Confirm = confirmer(config.Confirm)
+	h.Unlock = unlockUnattended
+	if err := h.Serve(os.Stdin, out); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type netrcCmd struct {
+	out   string
+	tag   string
+	check bool
+}
+
+func (*netrcCmd) Name() string {
+	return "netrc"
+}
+func (*netrcCmd) Synopsis() string {
+	return "write sites tagged netrc to ~/.netrc, or check it is up to date"
+}
+func (*netrcCmd) Usage() string {
+	return `netrc [-o ~/.netrc] [-tag netrc] [-check]
+  Writes a machine stanza for each site with the tag, in a block of its
+  own; hand-written stanzas outside the block are kept. With -check it
+  only reports how the block differs from the vault, failing if it does.
+`
+}
+func (n *netrcCmd) SetFlags(f *flag.FlagSet) {
+	dflt := ".netrc"
+	if home, err := os.UserHomeDir(); err == nil {
+		dflt = filepath.Join(home, ".netrc")
+	}
+	f.StringVar(&n.out, "o", dflt, "netrc file")
+	f.StringVar(&n.tag, "tag", baccounts.NetrcTag, "Tag of the sites to write")
+	f.BoolVar(&n.check, "check", false, "Report drift instead of writing")
+}
+func (n *netrcCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	netrc, err := os.ReadFile(n.out)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	entries, err := b.NetrcEntries(coder, n.tag)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if n.check {
+		drift := baccounts.NetrcDrift(string(netrc), entries)
+		for _, line := range drift {
+			fmt.Println(line)
+		}
+		if len(drift) > 0 {
+			fmt.Printf("%s is out of date: run 'baccounts netrc'\n", n.out)
+			return subcommands.ExitFailure
+		}
+		fmt.Println(n.out, "is up to date")
+		return subcommands.ExitSuccess
+	}
+
+	out := baccounts.ReplaceNetrcBlock(string(netrc), baccounts.NetrcBlock(entries))
+	if err := baccounts.WriteFileAtomic(n.out, []byte(out), 0600); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("Wrote %d machines to %s\n", len(entries), n.out)
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type otpSetCmd struct {
+	site      string
+	name      string
+	uri       string
+	algorithm string
+	digits    int
+	period    int
+	hotp      bool
+}
+
+func (*otpSetCmd) Name() string {
+	return "otp-set"
+}
+func (*otpSetCmd) Synopsis() string {
+	return "set the 2FA secret of the site"
+}
+func (*otpSetCmd) Usage() string {
+	return `otp-set -site site [-name name] [-uri otpauth://...] [-algorithm SHA1] [-digits 6] [-period 30] [-hotp]
+  Without -uri, the base32 secret is asked for.
+`
+}
+func (o *otpSetCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&o.site, "site", "", "Site of the secret (required)")
+	f.StringVar(&o.name, "name", "", "Profile name")
+	f.StringVar(&o.uri, "uri", "", "otpauth:// URI, as in the QR code")
+	f.StringVar(&o.algorithm, "algorithm", "SHA1", "SHA1, SHA256 or SHA512")
+	f.IntVar(&o.digits, "digits", 6, "Digits of a code")
+	f.IntVar(&o.period, "period", 30, "Seconds a code is valid")
+	f.BoolVar(&o.hotp, "hotp", false, "Counter-based HOTP instead of TOTP")
+}
+func (o *otpSetCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	p, site, err := findSite(b, o.name, o.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	var otp *baccounts.OTP
+	var secret string
+	if o.uri != "" {
+		otp, secret, err = baccounts.ParseOTPURI(o.uri)
+	} else {
+		otp = baccounts.NewOTP()
+		otp.Algorithm = strings.ToUpper(o.algorithm)
+		otp.Digits = o.digits
+		otp.Period = o.period
+		if o.hotp {
+			otp.Type = "hotp"
+		}
+		if secret, err = baccounts.ReadPassword("OTP secret (base32): "); err == nil {
+			err = otp.Validate(secret)
+		}
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	coder, err := b.Coder()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if otp.EncodedSecret, err = coder.Encode(secret, 0); err != nil {
+		fmt.Println("Can't encode secret:", err)
+		return subcommands.ExitFailure
+	}
+
+	site.OTP = otp
+	site.Touch()
+	b.SetMessage("Set OTP for %s @ %s", o.site, p.Name)
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("%s set for %s\n", strings.ToUpper(otp.Type), site.Url)
+	return subcommands.ExitSuccess
+}
+
+type otpCmd struct {
+	site string
+	name string
+}
+
+func (*otpCmd) Name() string {
+	return "otp"
+}
+func (*otpCmd) Synopsis() string {
+	return "copy the current 2FA code of the site to clipboard"
+}
+func (*otpCmd) Usage() string {
+	return `otp -site site [-name name]
+`
+}
+func (o *otpCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&o.site, "site", "", "Site of the code (required)")
+	f.StringVar(&o.name, "name", "", "Profile name")
+}
+func (o *otpCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	p, site, err := findSite(b, o.name, o.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if site.OTP == nil {
+		fmt.Println("No OTP for", site.Url, ": set one with otp-set")
+		return subcommands.ExitFailure
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	code, err := site.OTPCode(coder, time.Now())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if err := clipboard.WriteAll(code); err != nil {
+		fmt.Println("Failed to copy code to clipboard: ", err)
+		return subcommands.ExitFailure
+	}
+	if site.OTP.Type == "hotp" {
+		b.SetMessage("Advance HOTP counter for %s @ %s", o.site, p.Name)
+		if err := b.UpdateConfigFile(datafile); err != nil {
+			fmt.Println("Failed to save", datafile, err)
+			return subcommands.ExitFailure
+		}
+		fmt.Printf("Code for %s copied to clipboard (counter %d)\n", site.Url, site.OTP.Counter-1)
+		return subcommands.ExitSuccess
+	}
+	left := site.OTP.Remaining(time.Now())
+	fmt.Printf("Code for %s copied to clipboard, valid for %v\n", site.Url, left)
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,99 @@
+package baccounts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The passphrase agent keeps the passphrase of the GPG key for a while,
+// for SetPassphrase to take it from there instead of asking, e.g. when
+// baccounts runs as askpass without a terminal. Like ssh-agent, it hands
+// it to any process of the user that connects to its socket.
+
+type agentReply struct {
+	Passphrase string
+}
+
+// AgentSocket is where the agent listens: $BACCOUNTS_AGENT_SOCK, or else
+// in the runtime directory of the user.
+func AgentSocket() string {
+	if sock := os.Getenv("BACCOUNTS_AGENT_SOCK"); sock != "" {
+		return sock
+	}
+	return filepath.Join(runtimeDir(), "baccounts-agent.sock")
+}
+
+// runtimeDir is where sockets go: $XDG_RUNTIME_DIR, or else a directory
+// of the user in the temporary one.
+func runtimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("baccounts-%d", os.Getuid()))
+}
+
+// ListenSocket opens a Unix socket only accessible by the user, in a
+// directory only the user can access. A stale socket is replaced, but not
+// one something is still listening on.
+func ListenSocket(sock string) (net.Listener, error) {
+	dir := filepath.Dir(sock)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", sock, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("Something is listening on %s already", sock)
+	}
+	os.Remove(sock)
+
+	return listenUnix(sock)
+}
+
+// ServeAgent hands the passphrase to whoever connects to ln until ttl
+// has passed, or ln is closed.
+func ServeAgent(ln net.Listener, passphrase string, ttl time.Duration) error {
+	timer := time.AfterFunc(ttl, func() { ln.Close() })
+	defer timer.Stop()
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			json.NewEncoder(conn).Encode(&agentReply{passphrase})
+		}()
+	}
+}
+
+// AgentPassphrase asks the running agent for the passphrase.
+func AgentPassphrase() (string, error) {
+	// Not from an agent of someone else
+	sock := AgentSocket()
+	if err := checkSocketDir(filepath.Dir(sock)); err != nil {
+		return "", err
+	}
+	conn, err := net.DialTimeout("unix", sock, time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	var reply agentReply
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return "", err
+	}
+	return reply.Passphrase, nil
+}
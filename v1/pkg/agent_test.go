@@ -0,0 +1,89 @@
+package baccounts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// socketDir is a temporary directory ListenSocket takes.
+func socketDir(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestAgent(t *testing.T) {
+	sock := filepath.Join(socketDir(t), "agent.sock")
+	t.Setenv("BACCOUNTS_AGENT_SOCK", sock)
+
+	if _, err := AgentPassphrase(); err == nil {
+		t.Fatal("No agent should be running")
+	}
+
+	ln, err := ListenSocket(sock)
+	if err != nil {
+		t.Fatal("ListenSocket:", err)
+	}
+	done := make(chan error)
+	go func() { done <- ServeAgent(ln, "baccounts", 500*time.Millisecond) }()
+
+	if _, err := ListenSocket(sock); err == nil {
+		t.Error("Second agent should fail to listen")
+	}
+	pass, err := AgentPassphrase()
+	if err != nil || pass != "baccounts" {
+		t.Error("Unexpected passphrase", pass, err)
+	}
+	coder := NewTestCoder()
+	coder.passphrase = ""
+	coder.SetPassphrase()
+	if coder.passphrase != "baccounts" {
+		t.Error("SetPassphrase should take it from the agent")
+	}
+
+	if err := <-done; err != nil {
+		t.Error("ServeAgent:", err)
+	}
+	if _, err := AgentPassphrase(); err == nil {
+		t.Error("Agent should have stopped")
+	}
+}
+
+func TestCheckPassphrase(t *testing.T) {
+	coder := NewTestCoder()
+	if err := coder.CheckPassphrase("baccounts"); err != nil {
+		t.Error("CheckPassphrase:", err)
+	}
+	if err := coder.CheckPassphrase("wrong"); err == nil {
+		t.Error("Wrong passphrase accepted")
+	}
+}
+
+func TestListenSocketDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	if _, err := ListenSocket(sock); err == nil {
+		t.Error("Directory others can write should be refused")
+	}
+	t.Setenv("BACCOUNTS_AGENT_SOCK", sock)
+	if _, err := AgentPassphrase(); err == nil {
+		t.Error("Agent in a directory others can write should be refused")
+	}
+
+	sock = filepath.Join(socketDir(t), "new", "agent.sock")
+	ln, err := ListenSocket(sock)
+	if err != nil {
+		t.Fatal("ListenSocket:", err)
+	}
+	defer ln.Close()
+	if fi, err := os.Stat(sock); err != nil || fi.Mode().Perm() != 0600 {
+		t.Error("Unexpected mode of socket", fi.Mode(), err)
+	}
+}
@@ -0,0 +1,53 @@
+package baccounts
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// AskpassRule maps a prompt of ssh, sudo or git asking for a password
+// to the site whose password it wants.
+type AskpassRule struct {
+	Prompt  string // Regular expression the prompt has to match
+	Site    string // Site to look up, with $1 or ${name} of the match expanded
+	Profile string // Profile to look in, the default one if empty
+}
+
+// DefaultAskpassRules come after those of Config.Askpass. A prompt that
+// names a user only gets the password of that user's account.
+var DefaultAskpassRules = []*AskpassRule{
+	// ssh: "me@db.example.com's password: "
+	{Prompt: `^(\S+)@([^\s@']+)'s password:`, Site: "$1@$2"},
+	// git: "Password for 'https://me@github.com': "
+	{Prompt: `^Password for '(?:\w+://)?([^@'/]+)@([^/']+)`, Site: "$1@$2"},
+	// git: "Password for 'https://github.com': "
+	{Prompt: `^Password for '(?:\w+://)?([^@'/]+)(?:/[^']*)?'`, Site: "$1"},
+	// sudo: "[sudo] password for me: "
+	{Prompt: `^\[sudo\] password for (\S+):`, Site: "$1@localhost"},
+}
+
+// AskpassSite finds the site of the password the prompt asks for, by the
+// first of the rules that matches it and names a site of the exact
+// domain, see FindSiteExact.
+func (b *Baccount) AskpassSite(rules []*AskpassRule, prompt string) (*Profile, *Site, error) {
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Prompt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid askpass rule %q: %v", rule.Prompt, err)
+		}
+		m := re.FindStringSubmatchIndex(prompt)
+		if m == nil {
+			continue
+		}
+		pattern := string(re.ExpandString(nil, rule.Site, prompt, m))
+
+		p, err := b.GetProfile(rule.Profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, site, err := p.FindSiteExact(pattern); err == nil {
+			return p, site, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("No site for prompt %q", prompt)
+}
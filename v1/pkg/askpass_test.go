@@ -0,0 +1,49 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestAskpassSite(t *testing.T) {
+	me := NewProfile("me", true)
+	me.AddSite("db.example.com", "ssh://db.example.com", "alice", "pass-a", "alice@mac.com")
+	me.AddSite("db.example.com", "ssh://db.example.com", "admin", "pass-b", "admin@mac.com")
+	me.AddSite("github.com", "https://github.com", "alice", "pass-c", "alice@mac.com")
+	me.AddSite("localhost", "sudo://localhost", "alice", "pass-d", "alice@mac.com")
+	work := NewProfile("work", false)
+	work.AddSite("vpn.work.com", "https://vpn.work.com", "alice", "pass-e", "alice@work.com")
+	b := &Baccount{Profiles: []*Profile{me, work}}
+
+	rules := append([]*AskpassRule{
+		{Prompt: `^Enter VPN password`, Site: "vpn.work.com", Profile: "work"},
+	}, DefaultAskpassRules...)
+	for prompt, expected := range map[string]string{
+		"admin@db.example.com's password: ":         "pass-b",
+		"alice@github.com's password: ":             "pass-c",
+		"Password for 'https://alice@github.com': ": "pass-c",
+		"Password for 'https://github.com': ":       "pass-c",
+		"[sudo] password for alice: ":               "pass-d",
+		"Enter VPN password: ":                      "pass-e",
+	} {
+		_, site, err := b.AskpassSite(rules, prompt)
+		if err != nil || site.EncodedPass != expected {
+			t.Error("Unexpected site for", prompt, site, err)
+		}
+	}
+
+	for _, prompt := range []string{
+		"db.example.com's password: ",         // No user, unlike what ssh asks
+		"me@hub.com's password: ",             // Not exactly github.com
+		"Username for 'https://github.com': ", // Not a password
+		"[sudo] password for root: ",          // Not alice's
+		"bob@github.com's password: ",         // Not alice's
+		"Password for 'https://bob@github.com': ",
+	} {
+		if _, site, err := b.AskpassSite(rules, prompt); err == nil {
+			t.Error("No site expected for", prompt, site)
+		}
+	}
+	if _, _, err := b.AskpassSite([]*AskpassRule{{Prompt: "("}}, "x"); err == nil {
+		t.Error("Invalid rule should fail")
+	}
+}
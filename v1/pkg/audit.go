@@ -0,0 +1,114 @@
+package baccounts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuditOptions tells Audit what to report.
+type AuditOptions struct {
+	MinScore int           // Passwords with a lower EstimateStrength score are reported
+	MaxAge   time.Duration // Passwords not changed for longer are reported; 0 for no limit
+	Unknown  bool          // Passwords of unknown age are reported as old too
+	Breaches *BreachDB     // Passwords found in it are reported, if set
+}
+
+// Finding is one problem Audit found with the password of a site.
+type Finding struct {
+	Kind    string // "breached", "reused", "weak", "old" or "error"
+	Profile string
+	Domain  string
+	Detail  string
+}
+
+type AuditReport struct {
+	Sites    int
+	Findings []Finding
+}
+
+// Audit decrypts every password with coder, which must have the
+// passphrase set, and reports reused, weak and old ones.
+func (b *Baccount) Audit(coder *Coder, opts AuditOptions) (*AuditReport, error) {
+	report := &AuditReport{}
+	reused := make(map[string][]string)
+	now := time.Now()
+
+	for _, p := range b.Profiles {
+		for dom, site := range p.Sites {
+			report.Sites++
+			where := dom + " @ " + p.Name
+			pass, err := coder.Decode(site.EncodedPass)
+			if err != nil {
+				report.add("error", p.Name, dom, fmt.Sprintf("cannot decrypt: %v", err))
+				continue
+			}
+			reused[pass] = append(reused[pass], where)
+
+			if len(pass) < MinPassLength {
+				report.add("weak", p.Name, dom, fmt.Sprintf("shorter than %d chars", MinPassLength))
+			} else if strength := EstimateStrength(pass); strength.Score < opts.MinScore {
+				report.add("weak", p.Name, dom, fmt.Sprintf("%s: %s", strength, strings.Join(strength.Reasons, ", ")))
+			}
+
+			if opts.Breaches != nil {
+				n, err := opts.Breaches.Count(pass)
+				if err != nil {
+					return nil, err
+				}
+				if n > 0 {
+					report.add("breached", p.Name, dom, fmt.Sprintf("seen %d times in breaches", n))
+				}
+			}
+
+			if opts.MaxAge > 0 {
+				if site.PassModified.IsZero() {
+					// Saved before baccounts recorded it
+					if opts.Unknown {
+						report.add("old", p.Name, dom, "age unknown")
+					}
+				} else if age := now.Sub(site.PassModified); age > opts.MaxAge {
+					report.add("old", p.Name, dom, fmt.Sprintf("changed %d days ago", int(age.Hours()/24)))
+				}
+			}
+		}
+	}
+
+	for _, sites := range reused {
+		if len(sites) < 2 {
+			continue
+		}
+		sort.Strings(sites)
+		for _, where := range sites {
+			dom, profile, _ := strings.Cut(where, " @ ")
+			report.add("reused", profile, dom, fmt.Sprintf("same as %d sites: %s", len(sites)-1, others(sites, where)))
+		}
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Domain < b.Domain
+	})
+	return report, nil
+}
+
+func (r *AuditReport) add(kind, profile, domain, detail string) {
+	r.Findings = append(r.Findings, Finding{kind, profile, domain, detail})
+}
+
+func others(sites []string, self string) string {
+	rest := make([]string, 0, len(sites)-1)
+	for _, s := range sites {
+		if s != self {
+			rest = append(rest, s)
+		}
+	}
+	return strings.Join(rest, ", ")
+}
@@ -0,0 +1,76 @@
+package baccounts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	coder := NewTestCoder()
+
+	p := NewProfile("me", true)
+	for dom, pass := range map[string]string{
+		"a.com": "Vm3kQ9zLp2Xw7RtY",
+		"b.com": "Vm3kQ9zLp2Xw7RtY",
+		"c.com": "short",
+		"d.com": "Password1234",
+		"e.com": "Hq8Zn4Wc1Rv6Ty3B",
+	} {
+		enc, err := coder.Encode(pass, 0)
+		if err != nil {
+			t.Fatal("Encode:", err)
+		}
+		p.AddSite(dom, "https://"+dom, "me", enc, "me@mac.com")
+	}
+	p.Sites["e.com"].PassModified = time.Now().Add(-400 * 24 * time.Hour)
+	// Other changes don't make the password any younger
+	p.Sites["e.com"].Modified = time.Now()
+	b := &Baccount{Profiles: []*Profile{p}}
+
+	report, err := b.Audit(coder, AuditOptions{MinScore: MinScore, MaxAge: 365 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal("Audit:", err)
+	}
+	if report.Sites != 5 {
+		t.Error("Unexpected number of sites", report.Sites)
+	}
+
+	expected := []struct{ kind, domain string }{
+		{"old", "e.com"},
+		{"reused", "a.com"},
+		{"reused", "b.com"},
+		{"weak", "c.com"},
+		{"weak", "d.com"},
+	}
+	if len(report.Findings) != len(expected) {
+		t.Fatal("Unexpected findings", report.Findings)
+	}
+	for i, e := range expected {
+		f := report.Findings[i]
+		if f.Kind != e.kind || f.Domain != e.domain {
+			t.Error("Unexpected finding", i, f)
+		}
+	}
+}
+
+func TestAuditUnknownAge(t *testing.T) {
+	coder := NewTestCoder()
+	enc, err := coder.Encode("Hq8Zn4Wc1Rv6Ty3B", 0)
+	if err != nil {
+		t.Fatal("Encode:", err)
+	}
+	p := NewProfile("me", true)
+	p.AddSite("a.com", "https://a.com", "me", enc, "me@mac.com")
+	// As saved before PassModified was recorded
+	p.Sites["a.com"].PassModified = time.Time{}
+	b := &Baccount{Profiles: []*Profile{p}}
+
+	opts := AuditOptions{MaxAge: 365 * 24 * time.Hour}
+	if report, err := b.Audit(coder, opts); err != nil || len(report.Findings) != 0 {
+		t.Error("Unknown age should not be reported by default", report, err)
+	}
+	opts.Unknown = true
+	if report, err := b.Audit(coder, opts); err != nil || len(report.Findings) != 1 || report.Findings[0].Kind != "old" {
+		t.Error("Unknown age should be reported if asked", report, err)
+	}
+}
@@ -0,0 +1,66 @@
+package baccounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Fields of a site with an AWS access key, named like the keys of
+// ~/.aws/credentials. The session token and the expiration, an RFC 3339
+// time, are only for temporary keys.
+const (
+	AWSAccessKeyIdField     = "aws_access_key_id"
+	AWSSecretAccessKeyField = "aws_secret_access_key"
+	AWSSessionTokenField    = "aws_session_token"
+	AWSExpirationField      = "aws_expiration"
+)
+
+// AWSCredentials is what AWS SDKs read from a credential_process, which
+// has to leave out the session token and the expiration of a long-term
+// key.
+type AWSCredentials struct {
+	Version         int // Always 1
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string     `json:",omitempty"`
+	Expiration      *time.Time `json:",omitempty"`
+}
+
+// AWSCredentials decrypts the AWS access key of the site with coder.
+// An expired key is an error, for SDKs not to use it.
+func (site *Site) AWSCredentials(coder *Coder, now time.Time) (*AWSCredentials, error) {
+	creds := &AWSCredentials{Version: 1}
+	var err error
+	if creds.AccessKeyId, err = site.GetField(coder, AWSAccessKeyIdField); err != nil {
+		return nil, err
+	}
+	if creds.SecretAccessKey, err = site.GetField(coder, AWSSecretAccessKeyField); err != nil {
+		return nil, err
+	}
+	if _, ok := site.Fields[AWSSessionTokenField]; ok {
+		if creds.SessionToken, err = site.GetField(coder, AWSSessionTokenField); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := site.Fields[AWSExpirationField]; ok {
+		value, err := site.GetField(coder, AWSExpirationField)
+		if err != nil {
+			return nil, err
+		}
+		expiration, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s of %s: %v", AWSExpirationField, site.Url, err)
+		}
+		if !now.Before(expiration) {
+			return nil, fmt.Errorf("AWS credentials of %s expired at %v", site.Url, expiration)
+		}
+		creds.Expiration = &expiration
+	}
+	return creds, nil
+}
+
+func (c *AWSCredentials) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
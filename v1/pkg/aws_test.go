@@ -0,0 +1,52 @@
+package baccounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAWSCredentials(t *testing.T) {
+	coder := NewTestCoder()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	site := &Site{Url: "https://aws.amazon.com"}
+
+	if _, err := site.AWSCredentials(coder, now); err == nil {
+		t.Error("Site without a key should fail")
+	}
+	site.SetField(coder, AWSAccessKeyIdField, "AKIAEXAMPLE", false)
+	site.SetField(coder, AWSSecretAccessKeyField, "secret/key", true)
+	creds, err := site.AWSCredentials(coder, now)
+	if err != nil {
+		t.Fatal("AWSCredentials:", err)
+	}
+	var buf bytes.Buffer
+	if err := creds.Write(&buf); err != nil {
+		t.Fatal("Write:", err)
+	}
+	var parsed map[string]interface{}
+	json.Unmarshal(buf.Bytes(), &parsed)
+	if parsed["Version"] != 1.0 || parsed["AccessKeyId"] != "AKIAEXAMPLE" || parsed["SecretAccessKey"] != "secret/key" {
+		t.Error("Unexpected credentials", buf.String())
+	}
+	for _, name := range []string{"SessionToken", "Expiration"} {
+		if _, ok := parsed[name]; ok {
+			t.Error("Long-term key should have no", name, buf.String())
+		}
+	}
+
+	site.SetField(coder, AWSSessionTokenField, "token", true)
+	site.SetField(coder, AWSExpirationField, "2024-05-01T13:00:00Z", false)
+	creds, err = site.AWSCredentials(coder, now)
+	if err != nil || creds.SessionToken != "token" || !creds.Expiration.Equal(now.Add(time.Hour)) {
+		t.Error("Unexpected temporary credentials", creds, err)
+	}
+	if _, err := site.AWSCredentials(coder, now.Add(2*time.Hour)); err == nil {
+		t.Error("Expired credentials should fail")
+	}
+	site.SetField(coder, AWSExpirationField, "tomorrow", false)
+	if _, err := site.AWSCredentials(coder, now); err == nil {
+		t.Error("Invalid expiration should fail")
+	}
+}
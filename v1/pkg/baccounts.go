@@ -1,6 +1,7 @@
 package baccounts
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,9 +9,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/atotto/clipboard"
 	"github.com/google/subcommands"
+	"golang.org/x/crypto/ssh"
 )
 
 const Version = "0.1.0"
@@ -20,14 +23,35 @@ type Baccount struct {
 	DefaultMail string // Used for private key seek
 	Version     string
 	ReadOnly    bool
+	Git         bool     // Commit every save to the git repository around the datafile
+	Recipients  []string // Keys new secrets are encrypted to; all public keys if empty
+
+	message string   // Describes the pending change, used as the commit message
+	path    string   // Where the datafile was loaded from
+	loaded  []byte   // The datafile as loaded or last saved, see reconcile
+	lock    *os.File // Held from load to exit, see Lock
 }
 
 func (b *Baccount) List() error {
+	return b.ListSites(ListOptions{})
+}
+
+// ListSites lists sites, only those with the tag and in the folder if
+// given, as a tree of folders and profiles if tree.
+func (b *Baccount) ListSites(opts ListOptions) error {
+	if opts.Tree {
+		b.listTree(opts)
+		return nil
+	}
 	fmt.Println("default mail:", b.DefaultMail)
 	for _, acc := range b.Profiles {
 		fmt.Printf("%s default=%v\n", acc.Name, acc.Default)
 		for dom := range acc.Sites {
-			fmt.Printf("\t%s:\t%s\t%s\n", dom, acc.Sites[dom].Url, acc.Sites[dom].Mail)
+			site := acc.Sites[dom]
+			if !opts.match(site) {
+				continue
+			}
+			fmt.Printf("\t%s:\t%s\t%s%s\n", dom, site.Url, site.Mail, site.TagLabel())
 		}
 	}
 	return nil
@@ -43,6 +67,7 @@ func (b *Baccount) AddProfile(name, datafile string) error {
 	dflt := (len(b.Profiles) == 0)
 	b.Profiles = append(b.Profiles, NewProfile(name, dflt))
 
+	b.SetMessage("Add profile %s", name)
 	return b.UpdateConfigFile(datafile)
 }
 
@@ -57,7 +82,8 @@ func (b *Baccount) Update(name, site, datafile string) error {
 	}
 	fmt.Printf("Updating profile: %s @ %s\n", p.Name, site)
 
-	if _, err := p.FindSite(site); err != nil {
+	s, err := p.FindSite(site)
+	if err != nil {
 		return fmt.Errorf("Cannot find site %s: %v", site, err)
 	}
 
@@ -73,21 +99,25 @@ func (b *Baccount) Update(name, site, datafile string) error {
 		return fmt.Errorf("Password inputs don't match.")
 	}
 
-	if len(pass) < 8 {
-		return fmt.Errorf("New password should be longer than 8 chars (%d)\n", len(pass))
+	if strength, err := CheckNewPassword(pass); err != nil {
+		return err
+	} else if strength.Score < MinScore {
+		fmt.Printf("Warning: new password is %s: %s\n", strength, strings.Join(strength.Reasons, ", "))
 	}
 
-	coder := NewCoder()
+	coder, err := b.Coder()
+	if err != nil {
+		return err
+	}
 	encpass, err := coder.Encode(pass, 0)
 	if err != nil {
 		slog.Error("Can't encode pass", "encpass", encpass, "err", err)
 		return err
 	}
 
-	if err := p.UpdateSite(site, encpass); err != nil {
-		log.Println("Can't update profile:", err)
-		return err
-	}
+	s.EncodedPass = encpass
+	s.touchPass()
+	b.SetMessage("Update password for %s @ %s", site, p.Name)
 	if err := b.UpdateConfigFile(datafile); err != nil {
 		log.Println("Cannot update password file")
 		return err
@@ -96,6 +126,19 @@ func (b *Baccount) Update(name, site, datafile string) error {
 	return nil
 }
 
+// CheckNewPassword rejects passwords too short or far too guessable; a
+// Score below MinScore is left to the caller to warn about.
+func CheckNewPassword(pass string) (*Strength, error) {
+	if len(pass) < MinPassLength {
+		return nil, fmt.Errorf("New password should be longer than %d chars (%d)", MinPassLength, len(pass))
+	}
+	strength := EstimateStrength(pass)
+	if strength.Score < MinScore-1 {
+		return nil, fmt.Errorf("New password is %s: %s", strength, strings.Join(strength.Reasons, ", "))
+	}
+	return strength, nil
+}
+
 // ===============================================
 
 func (b *Baccount) GetDefault() (*Profile, error) {
@@ -121,6 +164,15 @@ func (b *Baccount) GetProfile(name string) (*Profile, error) {
 	return nil, errors.New("Profile not found:" + name)
 }
 
+// Coder returns a coder that encrypts to the recipients of this datafile.
+func (b *Baccount) Coder() (*Coder, error) {
+	coder := NewCoder()
+	if err := coder.SetRecipients(b.Recipients); err != nil {
+		return nil, err
+	}
+	return coder, nil
+}
+
 func (b *Baccount) toJson() (string, error) {
 	j, e := json.Marshal(b)
 	if e != nil {
@@ -129,39 +181,107 @@ func (b *Baccount) toJson() (string, error) {
 	return string(j), nil
 }
 
+// SetMessage describes the change the next UpdateConfigFile saves.
+func (b *Baccount) SetMessage(format string, a ...interface{}) {
+	b.message = fmt.Sprintf(format, a...)
+}
+
 func (b *Baccount) UpdateConfigFile(dest string) error {
+	if err := b.write(dest); err != nil {
+		return err
+	}
+	if b.Git {
+		if err := b.commit(dest); err != nil {
+			// Saved all the same; the next commit picks the change up
+			fmt.Fprintf(os.Stderr, "Warning: saved %s, but failed to commit it: %v\n", dest, err)
+		}
+	}
+	return nil
+}
+
+func (b *Baccount) write(dest string) error {
 	if b.ReadOnly {
 		return fmt.Errorf("read-only config: possibly, move it to $XDG_CONFIG_HOME/baccounts.json")
 	}
 
-	dir1, err := os.UserConfigDir()
-	if err != nil {
+	if b.isDatafile(dest) {
+		if b.lock == nil {
+			lock, err := Lock(dest)
+			if err != nil {
+				return err
+			}
+			b.lock = lock
+			defer b.Unlock()
+		}
+		if err := b.reconcile(dest); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	if err := encoder.Encode(b); err != nil {
 		return err
 	}
-	tmpfile := filepath.Join(dir1, "baccounts-temp.json")
-	if err := b.save(tmpfile); err != nil {
+	if err := backup(dest); err != nil {
 		return err
 	}
-	if err := os.Rename(tmpfile, dest); err != nil {
+	if err := WriteFileAtomic(dest, buf.Bytes(), 0600); err != nil {
 		fmt.Printf("Error on saving profiles: %v\n", err)
 		return err
 	}
+	b.loaded = buf.Bytes()
 	return nil
 }
 
-func (b *Baccount) save(datafile string) error {
+func (b *Baccount) Show(site *Site) subcommands.ExitStatus {
+	coder := NewCoder()
+	coder.SetPassphrase()
+	if err := b.CopyPass(coder, site); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("Pass for %s (%s) copied to clipboard\n", site.Url, site.Name)
+	return subcommands.ExitSuccess
+
+}
 
-	fp, err := os.OpenFile(datafile, os.O_RDWR|os.O_CREATE, 0600)
+// CopyPass decrypts the password of the site with coder, which has the
+// passphrase set, and copies it to clipboard.
+func (b *Baccount) CopyPass(coder *Coder, site *Site) error {
+	pass, err := coder.Decode(site.EncodedPass)
 	if err != nil {
 		return err
 	}
-	defer fp.Close()
+	if err := clipboard.WriteAll(pass); err != nil {
+		return fmt.Errorf("Failed to copy password to clipboard: %v", err)
+	}
+	return nil
+}
 
-	encoder := json.NewEncoder(fp)
-	return encoder.Encode(b)
+// ShowField copies a field of the site to clipboard, like Show does the password.
+func (b *Baccount) ShowField(site *Site, name string) subcommands.ExitStatus {
+	coder := NewCoder()
+	if field, ok := site.Fields[name]; ok && field.Secret {
+		coder.SetPassphrase()
+	}
+	value, err := site.GetField(coder, name)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	if e := clipboard.WriteAll(value); e != nil {
+		fmt.Println("Failed to copy field to clipboard: ", e)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("%s for %s (%s) copied to clipboard\n", name, site.Url, site.Name)
+	return subcommands.ExitSuccess
 }
 
-func (b *Baccount) Show(site *Site) subcommands.ExitStatus {
+// Info prints what is known about the site, with the strength of its
+// password instead of the password itself.
+func (b *Baccount) Info(site *Site) subcommands.ExitStatus {
 	coder := NewCoder()
 	coder.SetPassphrase()
 	pass, err := coder.Decode(site.EncodedPass)
@@ -170,27 +290,87 @@ func (b *Baccount) Show(site *Site) subcommands.ExitStatus {
 		return subcommands.ExitFailure
 	}
 
-	e := clipboard.WriteAll(pass)
-	if e != nil {
-		fmt.Println("Failed to copy password to clipboard: ", e)
-		return subcommands.ExitFailure
+	strength := EstimateStrength(pass)
+	fmt.Println("url:     ", site.Url)
+	fmt.Println("name:    ", site.Name)
+	fmt.Println("mail:    ", site.Mail)
+	fmt.Println("modified:", site.Modified)
+	if site.OTP != nil {
+		fmt.Printf("otp:      %s %s, %d digits\n", strings.ToUpper(site.OTP.Type), site.OTP.Algorithm, site.OTP.Digits)
+	}
+	if site.SSHKey != nil {
+		if pub, err := site.SSHKey.Public(); err == nil {
+			fmt.Printf("ssh key:  %s %s\n", pub.Type(), ssh.FingerprintSHA256(pub))
+		}
+	}
+	fmt.Println("strength:", strength)
+	for _, reason := range strength.Reasons {
+		fmt.Println("         -", reason)
+	}
+	for _, name := range site.FieldNames() {
+		if field := site.Fields[name]; field.Secret {
+			fmt.Printf("field:    %s (secret)\n", name)
+		} else {
+			fmt.Printf("field:    %s: %s\n", name, field.Value)
+		}
+	}
+	if site.EncodedNote != "" {
+		fmt.Println("note:     yes")
 	}
-	fmt.Printf("Pass for %s (%s) copied to clipboard\n", site.Url, site.Name)
 	return subcommands.ExitSuccess
+}
 
+// Init creates a new datafile with a default profile. mail is the
+// default mail and recipients are the keys secrets are encrypted to.
+func Init(datafile, mail string, recipients []string, profile string) (*Baccount, error) {
+	if err := os.MkdirAll(filepath.Dir(datafile), 0700); err != nil {
+		return nil, err
+	}
+	lock, err := Lock(datafile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(datafile); err == nil {
+		unlockFile(lock)
+		lock.Close()
+		return nil, fmt.Errorf("%s already exists", datafile)
+	}
+
+	b := &Baccount{
+		Profiles:    []*Profile{NewProfile(profile, true)},
+		DefaultMail: mail,
+		Version:     Version,
+		Recipients:  recipients,
+		path:        datafile,
+		lock:        lock,
+	}
+	b.SetMessage("Create %s", filepath.Base(datafile))
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		b.Unlock()
+		return nil, err
+	}
+	return b, nil
+}
+
+// DefaultDatafile is where LoadAccounts looks first, and where Init
+// creates a datafile unless told otherwise.
+func DefaultDatafile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "baccounts.json"), nil
 }
 
 func LoadAccounts() (*Baccount, string, error) {
 	// Try new config file
-	dir1, err := os.UserConfigDir()
+	datafile1, err := DefaultDatafile()
 	if err == nil {
-		datafile := filepath.Join(dir1, "baccounts.json")
-		b, err := LoadKeys(datafile)
+		b, err := LoadAccountsFrom(datafile1)
 		if err != nil {
 			log.Printf("Failed to load keys: %v", err)
 		} else {
-			b.ReadOnly = false
-			return b, datafile, nil
+			return b, datafile1, nil
 		}
 	}
 	// Try old config file
@@ -208,19 +388,62 @@ func LoadAccounts() (*Baccount, string, error) {
 	return b, datafile, nil
 }
 
+// LoadAccountsFrom loads a datafile given by the user, e.g. by Datafile,
+// for reading and writing, and locks it like LoadAccounts does.
+func LoadAccountsFrom(datafile string) (*Baccount, error) {
+	lock, err := Lock(datafile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := LoadKeys(datafile)
+	if err != nil {
+		unlockFile(lock)
+		lock.Close()
+		return nil, err
+	}
+	b.ReadOnly = false
+	b.lock = lock
+	return b, nil
+}
+
+// Reload reads the datafile again if another writer saved it since, for
+// long-running commands, which save their own changes at once, to see
+// theirs.
+func (b *Baccount) Reload() error {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, b.loaded) {
+		return nil
+	}
+	fresh, err := LoadKeys(b.path)
+	if err != nil {
+		return err
+	}
+	fresh.ReadOnly, fresh.lock = b.ReadOnly, b.lock
+	*b = *fresh
+	return nil
+}
+
+// discard drops changes made to b but not saved, by reading the
+// datafile again.
+func (b *Baccount) discard() error {
+	b.loaded = nil
+	return b.Reload()
+}
+
 func LoadKeys(datafile string) (*Baccount, error) {
-	fp, err := os.Open(datafile)
+	data, err := os.ReadFile(datafile)
 	if err != nil {
-		fmt.Printf("No such file as %s. Will create a new one\n", datafile)
-		// return &Baccount{make([]*Profile, 0, 16), nil, version}, nil
 		return nil, err
 	}
-	defer fp.Close()
 
 	var b Baccount
-	decoder := json.NewDecoder(fp)
-	if err := decoder.Decode(&b); err != nil {
+	if err := json.Unmarshal(data, &b); err != nil {
 		return nil, fmt.Errorf("Unable to parse json")
 	}
+	b.path = datafile
+	b.loaded = data
 	return &b, nil
 }
@@ -2,6 +2,7 @@ package baccounts
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 	"testing"
@@ -69,3 +70,63 @@ func TestSave(t *testing.T) {
 	//}
 	//log.Printf("read: %v", as)
 }
+
+func TestMultipleAccounts(t *testing.T) {
+	p := NewProfile("me", true)
+	if err := p.AddSite("github.com", "https://github.com", "me", "pass-a", "a@mac.com"); err != nil {
+		t.Fatal("AddSite:", err)
+	}
+	if err := p.AddSite("github.com", "https://github.com", "me", "pass-b", "b@mac.com"); err != nil {
+		t.Fatal("AddSite:", err)
+	}
+	if err := p.AddSite("github.com", "https://github.com", "me", "pass-c", "b@mac.com"); err == nil {
+		t.Error("Same account added twice")
+	}
+	if _, ok := p.Sites[SiteKey("github.com", "b@mac.com")]; !ok {
+		t.Error("Second account not keyed by mail", p.Sites)
+	}
+
+	if _, err := p.FindSite("github.com"); err == nil {
+		t.Error("Ambiguous pattern should be an error without ChooseSite")
+	}
+	for pattern, expected := range map[string]string{
+		"a@mac.com@github.com":           "pass-a",
+		"b@mac.com@github":               "pass-b",
+		"https://a%40mac.com@github.com": "pass-a",
+	} {
+		site, err := p.FindSite(pattern)
+		if err != nil || site.EncodedPass != expected {
+			t.Error("Unexpected site for", pattern, site, err)
+		}
+	}
+
+	ChooseSite = func(p *Profile, keys []string) (int, error) {
+		for i, key := range keys {
+			if p.Sites[key].Mail == "b@mac.com" {
+				return i, nil
+			}
+		}
+		return 0, errors.New("b@mac.com not given to choose")
+	}
+	defer func() { ChooseSite = nil }()
+	if err := p.UpdateSite("github.com", "pass-b2"); err != nil {
+		t.Fatal("UpdateSite:", err)
+	}
+	if p.Sites[SiteKey("github.com", "b@mac.com")].EncodedPass != "pass-b2" {
+		t.Error("Chosen site not updated")
+	}
+}
+
+func TestDeleteSite(t *testing.T) {
+	p := NewProfile("me", true)
+	p.AddSite("github.com", "https://github.com", "me", "pass-a", "a@mac.com")
+	if err := p.DeleteSite("github.com"); err != nil {
+		t.Fatal("DeleteSite:", err)
+	}
+	if len(p.Sites) != 0 {
+		t.Error("Site not deleted", p.Sites)
+	}
+	if err := p.DeleteSite("github.com"); err == nil {
+		t.Error("Deleting a missing site should fail")
+	}
+}
@@ -0,0 +1,123 @@
+package baccounts
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BreachDB is a Have I Been Pwned password dump of SHA-1 hashes ordered
+// by hash, with lines like "HASH:COUNT". It is binary-searched on disk,
+// as the dump is far larger than what should be read into memory.
+type BreachDB struct {
+	fp   *os.File
+	size int64
+}
+
+func OpenBreachDB(path string) (*BreachDB, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return &BreachDB{fp, info.Size()}, nil
+}
+
+func (db *BreachDB) Close() error {
+	return db.fp.Close()
+}
+
+// Count tells how many times pass was seen in breaches, 0 if never.
+func (db *BreachDB) Count(pass string) (int, error) {
+	sum := sha1.Sum([]byte(pass))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	lo, hi := int64(0), db.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, next, line, err := db.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		hash, count, _ := strings.Cut(line, ":")
+		switch c := strings.Compare(strings.ToUpper(hash), target); {
+		case c == 0:
+			n, err := strconv.Atoi(strings.TrimSpace(count))
+			if err != nil {
+				// Dumps without counts still tell it's breached
+				return 1, nil
+			}
+			return n, nil
+		case c < 0:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineAt finds the first line starting at or after off. It returns where
+// the line and the one after it start, and the line without its newline.
+func (db *BreachDB) lineAt(off int64) (int64, int64, string, error) {
+	start := off
+	if off > 0 {
+		nl, err := db.indexNewline(off - 1)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		if nl < 0 {
+			return db.size, db.size, "", nil
+		}
+		start = nl + 1
+	}
+	if start >= db.size {
+		return db.size, db.size, "", nil
+	}
+
+	end, err := db.indexNewline(start)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	next := end + 1
+	if end < 0 {
+		end, next = db.size, db.size
+	}
+	buf := make([]byte, end-start)
+	if _, err := db.fp.ReadAt(buf, start); err != nil && err != io.EOF {
+		return 0, 0, "", err
+	}
+	return start, next, strings.TrimRight(string(buf), "\r"), nil
+}
+
+// indexNewline returns the offset of the first '\n' at or after off, or
+// -1 if there is none.
+func (db *BreachDB) indexNewline(off int64) (int64, error) {
+	buf := make([]byte, 128)
+	for off < db.size {
+		n, err := db.fp.ReadAt(buf, off)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i), nil
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		off += int64(n)
+	}
+	return -1, nil
+}
@@ -0,0 +1,44 @@
+package baccounts
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBreachDB(t *testing.T) {
+	lines := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("password%d", i)))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenBreachDB(path)
+	if err != nil {
+		t.Fatal("OpenBreachDB:", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 1000; i++ {
+		n, err := db.Count(fmt.Sprintf("password%d", i))
+		if err != nil || n != i+1 {
+			t.Fatal("Unexpected count", i, n, err)
+		}
+	}
+	for _, pass := range []string{"", "password1000", "Vm3kQ9zLp2Xw7RtY"} {
+		n, err := db.Count(pass)
+		if err != nil || n != 0 {
+			t.Error("Unexpected count", pass, n, err)
+		}
+	}
+}
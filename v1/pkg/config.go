@@ -0,0 +1,101 @@
+package baccounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds settings that are not part of any datafile, read from
+// $XDG_CONFIG_HOME/baccounts-config.json when it exists.
+type Config struct {
+	Vaults  map[string]*Vault
+	Askpass []*AskpassRule    // Tried before DefaultAskpassRules
+	Tokens  map[string]string // Of clients of serve by their name; any client may connect if empty
+	Confirm []string          // Command asking yes or no, with the question appended, e.g. ["zenity", "--question", "--text"]
+}
+
+// Vault is a named datafile, e.g. to keep team credentials apart from
+// personal ones. Who can decrypt it is decided by Baccount.Recipients of
+// the datafile itself.
+type Vault struct {
+	File string
+}
+
+func ConfigFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "baccounts-config.json"), nil
+}
+
+// LoadConfig reads the config file; a missing one is an empty config.
+func LoadConfig() (*Config, error) {
+	file, err := ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %v", file, err)
+	}
+	for client, token := range c.Tokens {
+		// It would let in requests without one
+		if token == "" {
+			return nil, fmt.Errorf("Empty token of client %s in %s", client, file)
+		}
+	}
+	return &c, nil
+}
+
+// Datafile tells which datafile to use: file if given, else the one of
+// the named vault, else $BACCOUNTS_FILE. It returns "" when none of them
+// is set, for LoadAccounts to look up the default one.
+func Datafile(file, vault string) (string, error) {
+	if file != "" && vault != "" {
+		return "", fmt.Errorf("Give either a datafile or a vault, not both")
+	}
+	if file != "" {
+		return expandPath(file), nil
+	}
+	if vault == "" {
+		return expandPath(os.Getenv("BACCOUNTS_FILE")), nil
+	}
+
+	c, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	v, ok := c.Vaults[vault]
+	if !ok || v.File == "" {
+		return "", fmt.Errorf("No such vault: %s", vault)
+	}
+	path := expandPath(v.File)
+	if !filepath.IsAbs(path) {
+		file, err := ConfigFile()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(filepath.Dir(file), path)
+	}
+	return path, nil
+}
+
+func expandPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return os.ExpandEnv(path)
+}
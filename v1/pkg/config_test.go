@@ -0,0 +1,61 @@
+package baccounts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDatafile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("BACCOUNTS_FILE", "")
+
+	config := `{"Vaults": {"work": {"File": "work.json"}, "home": {"File": "/data/home.json"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "baccounts-config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		file, vault, env, expected string
+	}{
+		{"", "", "", ""},
+		{"", "", "/env.json", "/env.json"},
+		{"/flag.json", "", "/env.json", "/flag.json"},
+		{"", "work", "/env.json", filepath.Join(dir, "work.json")},
+		{"", "home", "", "/data/home.json"},
+	}
+	for _, c := range cases {
+		t.Setenv("BACCOUNTS_FILE", c.env)
+		datafile, err := Datafile(c.file, c.vault)
+		if err != nil || datafile != c.expected {
+			t.Error("Unexpected datafile", c, datafile, err)
+		}
+	}
+
+	if _, err := Datafile("", "nosuch"); err == nil {
+		t.Error("Unknown vault should be an error")
+	}
+	if _, err := Datafile("/flag.json", "work"); err == nil {
+		t.Error("Both file and vault should be an error")
+	}
+}
+
+func TestConfigTokens(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	file := filepath.Join(dir, "baccounts-config.json")
+
+	if err := os.WriteFile(file, []byte(`{"Tokens": {"deploy": "s3cret"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := LoadConfig(); err != nil || c.Tokens["deploy"] != "s3cret" {
+		t.Error("Unexpected config", c, err)
+	}
+	if err := os.WriteFile(file, []byte(`{"Tokens": {"deploy": "s3cret", "ci": ""}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Empty token should be an error")
+	}
+}
@@ -0,0 +1,79 @@
+package baccounts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Credential is a description of a credential in the git credential
+// helper protocol: lines of key=value, like protocol=https and
+// host=github.com, ended by a blank line.
+type Credential map[string]string
+
+// ReadCredential reads a credential from r up to a blank line or EOF.
+func ReadCredential(r io.Reader) (Credential, error) {
+	c := make(Credential)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("Invalid credential line: %q", line)
+		}
+		c[key] = value
+	}
+	return c, scanner.Err()
+}
+
+// Write writes the credential to w, keys sorted.
+func (c Credential) Write(w io.Writer) error {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.ContainsAny(c[key], "\n\x00") {
+			return fmt.Errorf("Invalid value of %s", key)
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", key, c[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL is the credential as a URL, with the username if given.
+func (c Credential) URL() *url.URL {
+	u := &url.URL{Scheme: c["protocol"], Host: c["host"], Path: c["path"]}
+	if c["username"] != "" {
+		u.User = url.User(c["username"])
+	}
+	return u
+}
+
+// FindCredential finds the site of the credential in the profile, whose
+// domain has to be the host, see FindSiteExact.
+func (p *Profile) FindCredential(c Credential) (string, *Site, error) {
+	if c["host"] == "" {
+		return "", nil, fmt.Errorf("No host in credential")
+	}
+	return p.FindSiteExact(c.URL().String())
+}
+
+// Username is how the site is logged in to: its mail, or its name
+// without one, like NetrcEntries. generate leaves the profile name in
+// Name, which is no login.
+func (site *Site) Username() string {
+	if site.Mail != "" {
+		return site.Mail
+	}
+	return site.Name
+}
@@ -0,0 +1,66 @@
+package baccounts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCredential(t *testing.T) {
+	in := "protocol=https\nhost=github.com\npath=me/repo.git\nwwwauth[]=Basic realm=\"GitHub\"\n\nignored=1\n"
+	c, err := ReadCredential(strings.NewReader(in))
+	if err != nil {
+		t.Fatal("ReadCredential:", err)
+	}
+	if c["protocol"] != "https" || c["host"] != "github.com" || c["path"] != "me/repo.git" || c["wwwauth[]"] != "Basic realm=\"GitHub\"" {
+		t.Error("Unexpected credential", c)
+	}
+	if _, ok := c["ignored"]; ok {
+		t.Error("Read past the blank line", c)
+	}
+	if _, err := ReadCredential(strings.NewReader("garbage\n")); err == nil {
+		t.Error("Line without = should fail")
+	}
+
+	var out bytes.Buffer
+	if err := (Credential{"username": "me", "password": "secret"}).Write(&out); err != nil {
+		t.Fatal("Write:", err)
+	}
+	if out.String() != "password=secret\nusername=me\n" {
+		t.Error("Unexpected output", out.String())
+	}
+	if err := (Credential{"password": "a\nb"}).Write(&out); err == nil {
+		t.Error("Newline in a value should fail")
+	}
+}
+
+func TestFindCredential(t *testing.T) {
+	p := NewProfile("me", true)
+	p.AddSite("github.com", "https://github.com", "me", "pass-a", "a@mac.com")
+	p.AddSite("github.com", "https://github.com", "other", "pass-b", "b@mac.com")
+	p.AddSite("git.example.com:8443", "https://git.example.com:8443", "", "pass-c", "c@mac.com")
+
+	cases := []struct {
+		c        Credential
+		expected string
+	}{
+		{Credential{"protocol": "https", "host": "github.com", "username": "other"}, "pass-b"},
+		{Credential{"protocol": "https", "host": "github.com", "username": "a@mac.com", "path": "me/repo.git"}, "pass-a"},
+		{Credential{"protocol": "https", "host": "git.example.com:8443"}, "pass-c"},
+	}
+	for _, c := range cases {
+		_, site, err := p.FindCredential(c.c)
+		if err != nil || site.EncodedPass != c.expected {
+			t.Error("Unexpected site for", c.c, site, err)
+		}
+	}
+	if _, _, err := p.FindCredential(Credential{"protocol": "https", "host": "hub.com"}); err == nil {
+		t.Error("Partial host match should fail")
+	}
+	if _, _, err := p.FindCredential(Credential{"protocol": "https", "host": "github.com"}); err == nil {
+		t.Error("Ambiguous host should fail without ChooseSite")
+	}
+	if _, site, _ := p.FindCredential(Credential{"protocol": "https", "host": "git.example.com:8443"}); site.Username() != "c@mac.com" {
+		t.Error("Username should be the mail", site)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"golang.org/x/crypto/openpgp"
 
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 
 	"syscall"
 
@@ -21,10 +23,11 @@ type Coder struct {
 	gpgDir        string
 	passphrase    string
 	publicKeyring openpgp.EntityList
+	recipients    openpgp.EntityList // Encode to these if set, see SetRecipients
 }
 
 func NewTestCoder() *Coder {
-	c := &Coder{"../keys/", "baccounts", nil}
+	c := &Coder{gpgDir: "../keys/", passphrase: "baccounts"}
 
 	publicKeyring := c.gpgDir + "pubring.gpg"
 
@@ -55,9 +58,8 @@ func NewCoder() *Coder {
 	}
 
 	coder := &Coder{
-		gpgDir,
-		"",
-		entityList,
+		gpgDir:        gpgDir,
+		publicKeyring: entityList,
 	}
 	fmt.Println("Public keyring:", coder.PublicKeyringFile())
 	fmt.Println("Secret Keyring:", coder.SecretKeyringFile())
@@ -71,9 +73,22 @@ func (coder *Coder) SecretKeyringFile() string {
 	return coder.gpgDir + "secring.gpg"
 }
 
+// ReadPassword reads a password without echo from the terminal: stdin,
+// or the controlling terminal if stdin is e.g. a helper protocol pipe.
 func ReadPassword(msg string) (string, error) {
+	fd := int(syscall.Stdin)
+	if !terminal.IsTerminal(fd) {
+		if tty, err := os.Open("/dev/tty"); err == nil {
+			defer tty.Close()
+			fd = int(tty.Fd())
+		}
+	}
+	if !foreground(fd) {
+		fmt.Println("Can't read password:", errBackground)
+		return "", errBackground
+	}
 	fmt.Printf(msg)
-	bytes, err := terminal.ReadPassword(int(syscall.Stdin))
+	bytes, err := terminal.ReadPassword(fd)
 	fmt.Println()
 	if err != nil {
 		fmt.Printf("Can't read password: %v\n", err)
@@ -82,7 +97,29 @@ func ReadPassword(msg string) (string, error) {
 	return string(bytes), nil
 }
 
+var errBackground = errors.New("Not in the foreground of the terminal")
+
+// OpenTTY opens the terminal to ask on, unless the process is a
+// background job of it, which would be stopped for reading it.
+func OpenTTY() (*os.File, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !foreground(int(tty.Fd())) {
+		tty.Close()
+		return nil, errBackground
+	}
+	return tty, nil
+}
+
+// SetPassphrase takes the passphrase from the agent if one is running,
+// and asks for it otherwise.
 func (coder *Coder) SetPassphrase() {
+	if pass, err := AgentPassphrase(); err == nil {
+		coder.passphrase = pass
+		return
+	}
 	pass, err := ReadPassword("Passphrase of your GPG key:")
 	if err != nil {
 		log.Fatalf("Can't read password: %v", err)
@@ -90,12 +127,53 @@ func (coder *Coder) SetPassphrase() {
 	coder.passphrase = pass
 }
 
+// SetCheckedPassphrase is SetPassphrase for long-running commands, which
+// fail early if the passphrase is wrong.
+func (coder *Coder) SetCheckedPassphrase() error {
+	coder.SetPassphrase()
+	return coder.CheckPassphrase(coder.passphrase)
+}
+
+// SetRecipients makes Encode encrypt to the public keys matching names
+// instead of to the keyring. An empty list restores the default.
+func (coder *Coder) SetRecipients(names []string) error {
+	recipients := make(openpgp.EntityList, 0, len(names))
+	for _, name := range names {
+		entity := coder.FindKey(name)
+		if entity == nil {
+			return fmt.Errorf("No public key found for recipient %s", name)
+		}
+		recipients = append(recipients, entity)
+	}
+	coder.recipients = recipients
+	return nil
+}
+
+// FindKey looks a public key up by mail address, name or key ID.
+func (coder *Coder) FindKey(name string) *openpgp.Entity {
+	keyID := strings.TrimPrefix(strings.ToUpper(name), "0X")
+	for _, entity := range coder.publicKeyring {
+		if entity.PrimaryKey.KeyIdString() == keyID || entity.PrimaryKey.KeyIdShortString() == keyID {
+			return entity
+		}
+		for _, identity := range entity.Identities {
+			if identity.UserId.Email == name || identity.UserId.Name == name || identity.Name == name {
+				return entity
+			}
+		}
+	}
+	return nil
+}
+
 func (coder *Coder) HasPubKey(id int) bool {
 	return (id > 0 && len(coder.publicKeyring) > id)
 }
 func (coder *Coder) Encode(txt string, id int) (string, error) {
 	slog.Info("Encode", "key", coder.publicKeyring[id])
 	keys := coder.publicKeyring[id:]
+	if len(coder.recipients) > 0 {
+		keys = coder.recipients
+	}
 
 	buf := new(bytes.Buffer)
 	w, err := openpgp.Encrypt(buf, keys, nil, nil, nil)
@@ -118,6 +196,26 @@ func (coder *Coder) Encode(txt string, id int) (string, error) {
 	return encStr, nil
 }
 
+// CheckPassphrase tells if pass unlocks the secret key.
+func (coder *Coder) CheckPassphrase(pass string) error {
+	keyringFileBuffer, err := os.Open(coder.SecretKeyringFile())
+	if err != nil {
+		return err
+	}
+	defer keyringFileBuffer.Close()
+	entityList, err := openpgp.ReadKeyRing(keyringFileBuffer)
+	if err != nil {
+		return err
+	}
+	if len(entityList) == 0 || entityList[0].PrivateKey == nil {
+		return fmt.Errorf("No secret key in %s", coder.SecretKeyringFile())
+	}
+	if err := entityList[0].PrivateKey.Decrypt([]byte(pass)); err != nil {
+		return fmt.Errorf("Wrong passphrase")
+	}
+	return nil
+}
+
 func (coder *Coder) Decode(txt string) (string, error) {
 	//secretKeyring := os.ExpandEnv("$HOME/.gnupg/secring.gpg")
 	// secretKeyring := "./keys/secring.gpg"
@@ -28,6 +28,30 @@ func TestEnc(t *testing.T) {
 	}
 }
 
+func TestRecipients(t *testing.T) {
+	coder := NewTestCoder()
+
+	if err := coder.SetRecipients([]string{"nobody@example.com"}); err == nil {
+		t.Fatal("Unknown recipient should be an error")
+	}
+	if err := coder.SetRecipients([]string{"kuenishi@example.com"}); err != nil {
+		t.Fatal("SetRecipients:", err)
+	}
+
+	s := "for kuenishi only"
+	encStr, e := coder.Encode(s, 0)
+	if e != nil {
+		t.Fatal("Error:", e)
+	}
+	decStr, e := coder.Decode(encStr)
+	if e != nil {
+		t.Fatal("Decrypt fail:", e)
+	}
+	if s != decStr {
+		t.Fatal("no match", decStr)
+	}
+}
+
 func TestShowKeys(t *testing.T) {
 	log.Println("pubring")
 	ShowKeys("./keys/pubring.gpg")
@@ -0,0 +1,105 @@
+package baccounts
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// DockerTag marks sites that are credentials of container registries,
+// kept by the docker credential helper.
+const DockerTag = "docker"
+
+// ErrRegistryNotFound is what docker expects from a helper that has no
+// credentials for a registry, word for word.
+var ErrRegistryNotFound = errors.New("credentials not found in native keychain")
+
+// RegistryCredential is the JSON of the docker credential helper
+// protocol, whose field names it shares.
+type RegistryCredential struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// RegistryHost is the host of a registry server URL, which comes with or
+// without a scheme, like https://index.docker.io/v1/ or ghcr.io.
+func RegistryHost(serverURL string) string {
+	host := serverURL
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.ToLower(host)
+}
+
+// FindRegistry finds the credentials of the registry among sites tagged
+// DockerTag, by the server URL they were stored with or else by host.
+func (p *Profile) FindRegistry(serverURL string) (string, *Site, error) {
+	found := ""
+	for _, key := range p.registryKeys() {
+		site := p.Sites[key]
+		if site.Url == serverURL {
+			return key, site, nil
+		}
+		if found == "" && SiteDomain(key) == RegistryHost(serverURL) {
+			found = key
+		}
+	}
+	if found == "" {
+		return "", nil, ErrRegistryNotFound
+	}
+	return found, p.Sites[found], nil
+}
+
+// Registries maps the server URLs of all registry credentials to their
+// usernames, for the list operation.
+func (p *Profile) Registries() map[string]string {
+	registries := make(map[string]string)
+	for _, key := range p.registryKeys() {
+		site := p.Sites[key]
+		registries[site.Url] = site.Name
+	}
+	return registries
+}
+
+// StoreRegistry saves credentials of the registry, replacing those it
+// already has.
+func (p *Profile) StoreRegistry(coder *Coder, c *RegistryCredential) error {
+	if c.ServerURL == "" || RegistryHost(c.ServerURL) == "" {
+		return errors.New("No server URL given")
+	}
+	if _, site, err := p.FindRegistry(c.ServerURL); err == nil {
+		site.Name = c.Username
+		return site.SetPassword(coder, c.Secret)
+	}
+
+	// A site of the host may exist already, e.g. to log in to its web UI
+	key := RegistryHost(c.ServerURL)
+	if _, ok := p.Sites[key]; ok {
+		key = SiteKey(key, c.Username)
+	}
+	if _, ok := p.Sites[key]; ok {
+		// Not one of ours, as FindRegistry found none: keep its password
+		return errors.New("Site already exists: " + key + " (not a docker login)")
+	}
+	site := &Site{Url: c.ServerURL, Name: c.Username, Tags: []string{DockerTag}}
+	if err := site.SetPassword(coder, c.Secret); err != nil {
+		return err
+	}
+	p.Sites[key] = site
+	return nil
+}
+
+func (p *Profile) registryKeys() []string {
+	keys := make([]string, 0)
+	for key, site := range p.Sites {
+		if site.HasTag(DockerTag) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	for serverURL, expected := range map[string]string{
+		"https://index.docker.io/v1/": "index.docker.io",
+		"ghcr.io":                     "ghcr.io",
+		"Registry.example.com:5000/x": "registry.example.com:5000",
+	} {
+		if host := RegistryHost(serverURL); host != expected {
+			t.Error("Unexpected host of", serverURL, host)
+		}
+	}
+}
+
+func TestStoreRegistry(t *testing.T) {
+	coder := NewTestCoder()
+	p := NewProfile("me", true)
+	p.AddSite("ghcr.io", "https://ghcr.io", "web", "pass-web", "me@mac.com")
+
+	if _, _, err := p.FindRegistry("ghcr.io"); err != ErrRegistryNotFound {
+		t.Error("Untagged site should not be a registry", err)
+	}
+	if err := p.StoreRegistry(coder, &RegistryCredential{"ghcr.io", "me", "token-1"}); err != nil {
+		t.Fatal("StoreRegistry:", err)
+	}
+	if err := p.StoreRegistry(coder, &RegistryCredential{"https://index.docker.io/v1/", "me", "token-2"}); err != nil {
+		t.Fatal("StoreRegistry:", err)
+	}
+	if err := p.StoreRegistry(coder, &RegistryCredential{"ghcr.io", "me2", "token-3"}); err != nil {
+		t.Fatal("StoreRegistry:", err)
+	}
+	if len(p.Sites) != 3 {
+		t.Error("Storing again should replace", p.Sites)
+	}
+
+	key, site, err := p.FindRegistry("https://ghcr.io")
+	if err != nil || key != SiteKey("ghcr.io", "me") || site.Name != "me2" {
+		t.Fatal("Unexpected registry", key, site, err)
+	}
+	if secret, err := coder.Decode(site.EncodedPass); err != nil || secret != "token-3" {
+		t.Error("Unexpected secret", secret, err)
+	}
+
+	registries := p.Registries()
+	if len(registries) != 2 || registries["https://index.docker.io/v1/"] != "me" || registries["ghcr.io"] != "me2" {
+		t.Error("Unexpected registries", registries)
+	}
+
+	// Another login of the same user on the host is left alone
+	p.AddSite("quay.io", "https://quay.io", "web", "pass-web", "me@mac.com")
+	p.AddSite("quay.io", "https://quay.io", "web", "pass-bob", "bob")
+	if err := p.StoreRegistry(coder, &RegistryCredential{"quay.io", "bob", "token-4"}); err == nil {
+		t.Error("StoreRegistry should not replace a site it didn't store")
+	}
+	if site := p.Sites[SiteKey("quay.io", "bob")]; site.EncodedPass != "pass-bob" || site.HasTag(DockerTag) {
+		t.Error("Site replaced", site)
+	}
+}
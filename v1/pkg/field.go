@@ -0,0 +1,87 @@
+package baccounts
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Field is a named value of a site besides its password, like a PIN,
+// an account number or recovery codes. Secret ones are encrypted by the
+// Coder, plain ones are kept as they are.
+type Field struct {
+	Value  string
+	Secret bool
+}
+
+// SetField adds or replaces a field, encrypting value with coder if secret.
+func (site *Site) SetField(coder *Coder, name, value string, secret bool) error {
+	if name == "" {
+		return fmt.Errorf("Field name cannot be empty")
+	}
+	if secret {
+		enc, err := coder.Encode(value, 0)
+		if err != nil {
+			return err
+		}
+		value = enc
+	}
+	if site.Fields == nil {
+		site.Fields = make(map[string]*Field)
+	}
+	site.Fields[name] = &Field{value, secret}
+	site.Touch()
+	return nil
+}
+
+// GetField returns the value of a field, decrypted with coder if secret.
+func (site *Site) GetField(coder *Coder, name string) (string, error) {
+	field, ok := site.Fields[name]
+	if !ok {
+		return "", fmt.Errorf("No field %s in %s", name, site.Url)
+	}
+	if !field.Secret {
+		return field.Value, nil
+	}
+	return coder.Decode(field.Value)
+}
+
+func (site *Site) DeleteField(name string) error {
+	if _, ok := site.Fields[name]; !ok {
+		return fmt.Errorf("No field %s in %s", name, site.Url)
+	}
+	delete(site.Fields, name)
+	site.Touch()
+	return nil
+}
+
+// FieldNames lists the names of the fields, sorted.
+func (site *Site) FieldNames() []string {
+	names := make([]string, 0, len(site.Fields))
+	for name := range site.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetNote replaces the note, encrypted with coder; an empty note removes it.
+func (site *Site) SetNote(coder *Coder, note string) error {
+	enc := ""
+	if note != "" {
+		var err error
+		if enc, err = coder.Encode(note, 0); err != nil {
+			return err
+		}
+	}
+	site.EncodedNote = enc
+	site.Touch()
+	return nil
+}
+
+// Note decrypts the note with coder; it is empty if there's none.
+func (site *Site) Note(coder *Coder) (string, error) {
+	if site.EncodedNote == "" {
+		return "", nil
+	}
+	return coder.Decode(site.EncodedNote)
+}
@@ -0,0 +1,47 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	coder := NewTestCoder()
+	site := &Site{Url: "https://bank.example.com"}
+
+	if err := site.SetField(coder, "account", "1234-5678", false); err != nil {
+		t.Fatal("SetField:", err)
+	}
+	if err := site.SetField(coder, "pin", "0000", true); err != nil {
+		t.Fatal("SetField:", err)
+	}
+	if site.Fields["account"].Value != "1234-5678" {
+		t.Error("Plain field should be kept as is")
+	}
+	if site.Fields["pin"].Value == "0000" {
+		t.Error("Secret field should be encrypted")
+	}
+
+	for name, expected := range map[string]string{"account": "1234-5678", "pin": "0000"} {
+		value, err := site.GetField(coder, name)
+		if err != nil || value != expected {
+			t.Error("Unexpected value", name, value, err)
+		}
+	}
+	if names := site.FieldNames(); len(names) != 2 || names[0] != "account" {
+		t.Error("Unexpected names", names)
+	}
+
+	if err := site.DeleteField("account"); err != nil {
+		t.Error("DeleteField:", err)
+	}
+	if _, err := site.GetField(coder, "account"); err == nil {
+		t.Error("Deleted field still there")
+	}
+
+	if err := site.SetNote(coder, "security question: first pet"); err != nil {
+		t.Fatal("SetNote:", err)
+	}
+	if note, err := site.Note(coder); err != nil || note != "security question: first pet" {
+		t.Error("Unexpected note", note, err)
+	}
+}
@@ -0,0 +1,127 @@
+package baccounts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeepBackups is how many previous versions of a datafile are kept next
+// to it when saving.
+var KeepBackups = 5
+
+const backupSuffix = ".bak"
+
+// WriteFileAtomic writes data to a temporary file in the directory of
+// path and renames it onto path, syncing both the file and the directory,
+// so path has either the old or the new contents even after a crash.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	fp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpfile := fp.Name()
+	defer os.Remove(tmpfile)
+
+	if err := fp.Chmod(perm); err != nil {
+		fp.Close()
+		return err
+	}
+	if _, err := fp.Write(data); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Sync(); err != nil {
+		fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpfile, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// Directories can't be opened for syncing there
+		return nil
+	}
+	fp, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return fp.Sync()
+}
+
+// backup copies the current contents of path to a timestamped file next
+// to it, and removes the oldest ones beyond KeepBackups.
+func backup(path string) error {
+	if KeepBackups <= 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405.000000000")
+	if err := WriteFileAtomic(path+"."+stamp+backupSuffix, data, 0600); err != nil {
+		return err
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= KeepBackups {
+		return nil
+	}
+	for _, old := range backups[KeepBackups:] {
+		if err := os.Remove(old); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backups lists the backups of path, newest first.
+func Backups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*" + backupSuffix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches, nil
+}
+
+// RestoreBackup replaces the contents of b, loaded from dest, with a
+// backup and saves it. The version being replaced is itself backed up.
+func (b *Baccount) RestoreBackup(dest, backup string) error {
+	if !strings.HasPrefix(backup, dest+".") || !strings.HasSuffix(backup, backupSuffix) {
+		return fmt.Errorf("%s is not a backup of %s", backup, dest)
+	}
+	restored, err := LoadKeys(backup)
+	if err != nil {
+		return err
+	}
+
+	restored.ReadOnly = b.ReadOnly
+	restored.path, restored.loaded, restored.lock = b.path, b.loaded, b.lock
+	*b = *restored
+	b.SetMessage("Restore %s", filepath.Base(backup))
+	return b.UpdateConfigFile(dest)
+}
@@ -0,0 +1,74 @@
+package baccounts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out")
+
+	if err := WriteFileAtomic(path, []byte("longer content"), 0600); err != nil {
+		t.Fatal("WriteFileAtomic:", err)
+	}
+	if err := WriteFileAtomic(path, []byte("short"), 0600); err != nil {
+		t.Fatal("WriteFileAtomic:", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "short" {
+		t.Error("Unexpected contents", string(data), err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Error("Unexpected mode", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Error("Temporary file left behind", entries)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+
+	p := NewProfile("me", true)
+	b := &Baccount{Profiles: []*Profile{p}, Version: Version}
+	for i := 0; i < KeepBackups+3; i++ {
+		if err := b.UpdateConfigFile(datafile); err != nil {
+			t.Fatal("UpdateConfigFile:", err)
+		}
+		if i == 0 {
+			p.AddSite("a.com", "https://a.com", "me", "pass-a", "me@mac.com")
+		}
+	}
+
+	backups, err := Backups(datafile)
+	if err != nil {
+		t.Fatal("Backups:", err)
+	}
+	if len(backups) != KeepBackups {
+		t.Fatal("Unexpected number of backups", backups)
+	}
+
+	b, err = LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	oldest := backups[len(backups)-1]
+	if err := b.RestoreBackup(datafile, oldest); err != nil {
+		t.Fatal("RestoreBackup:", err)
+	}
+	if err := b.RestoreBackup(datafile, "/etc/passwd"); err == nil {
+		t.Error("Restoring from a non-backup should fail")
+	}
+
+	b, err = LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	if _, ok := b.Profiles[0].Sites["a.com"]; !ok {
+		t.Error("Restored backup should have a.com", oldest)
+	}
+}
@@ -0,0 +1,168 @@
+package baccounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// git runs a git command in dir and returns its trimmed stdout.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GitInit makes the directory of the datafile a git repository, if it is
+// not one yet, and commits the datafile. remote, when not empty, is added
+// as "origin" for Sync.
+func (b *Baccount) GitInit(datafile, remote string) error {
+	dir := filepath.Dir(datafile)
+	if _, err := git(dir, "rev-parse", "--git-dir"); err != nil {
+		if err := ownDir(datafile); err != nil {
+			return err
+		}
+		if _, err := git(dir, "init"); err != nil {
+			return err
+		}
+	}
+	if remote != "" {
+		if _, err := git(dir, "remote", "add", "origin", remote); err != nil {
+			return err
+		}
+	}
+
+	b.Git = true
+	b.SetMessage("Start tracking %s", filepath.Base(datafile))
+	return b.UpdateConfigFile(datafile)
+}
+
+// ownDir fails unless the directory of the datafile holds nothing but
+// the datafile and the files saving it leaves next to it, so that e.g.
+// all of ~/.config doesn't become a repository.
+func ownDir(datafile string) error {
+	dir, file := filepath.Split(datafile)
+	entries, err := os.ReadDir(filepath.Dir(datafile))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == file || strings.HasPrefix(name, file+".") || strings.HasPrefix(name, "."+file+".") {
+			continue
+		}
+		return fmt.Errorf("Won't make %s a git repository, it holds %s too: move %s to a directory of its own", dir, name, file)
+	}
+	return nil
+}
+
+// commit records the datafile in git, with the message given by SetMessage.
+func (b *Baccount) commit(datafile string) error {
+	dir, file := filepath.Split(datafile)
+	status, err := git(dir, "status", "--porcelain", "--", file)
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return nil
+	}
+
+	msg := b.message
+	if msg == "" {
+		msg = "Update " + file
+	}
+	if _, err := git(dir, "add", "--", file); err != nil {
+		return err
+	}
+	if _, err := git(dir, "commit", "-m", msg, "--", file); err != nil {
+		return err
+	}
+	b.message = ""
+	return nil
+}
+
+// Sync fetches the remote, merges its datafile into ours with Merge, and
+// pushes the result back. Merging is done on the JSON, so git only ever
+// sees a merge commit with our merged version as the result.
+func (b *Baccount) Sync(datafile, remote string, resolve Resolver) (*MergeSummary, error) {
+	if !b.Git {
+		return nil, errors.New("Datafile is not tracked by git: run git-init first")
+	}
+	dir, file := filepath.Split(datafile)
+
+	if err := b.commit(datafile); err != nil {
+		return nil, err
+	}
+	branch, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := git(dir, "fetch", remote); err != nil {
+		return nil, err
+	}
+
+	summary := &MergeSummary{}
+	tracking := remote + "/" + branch
+	if _, err := git(dir, "rev-parse", "--verify", "--quiet", tracking); err == nil {
+		if _, err := git(dir, "merge-base", "--is-ancestor", tracking, "HEAD"); err != nil {
+			summary, err = b.mergeRemote(dir, file, tracking, resolve)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := git(dir, "push", remote, branch); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+func (b *Baccount) mergeRemote(dir, file, tracking string, resolve Resolver) (*MergeSummary, error) {
+	theirs, err := git(dir, "show", tracking+":./"+file)
+	if err != nil {
+		return nil, err
+	}
+	var other Baccount
+	if err := json.Unmarshal([]byte(theirs), &other); err != nil {
+		return nil, fmt.Errorf("Unable to parse json of %s: %v", tracking, err)
+	}
+
+	summary, err := b.Merge(&other, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the remote as merged but keep our tree, then replace the
+	// datafile with the semantically merged one before committing.
+	if _, err := git(dir, "merge", "--no-ff", "--no-commit", "--allow-unrelated-histories", "-s", "ours", tracking); err != nil {
+		return nil, err
+	}
+	if err := b.commitMerge(dir, file, tracking); err != nil {
+		// Don't leave the repository mid-merge for the next sync
+		git(dir, "merge", "--abort")
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (b *Baccount) commitMerge(dir, file, tracking string) error {
+	if err := b.write(filepath.Join(dir, file)); err != nil {
+		return err
+	}
+	if _, err := git(dir, "add", "--", file); err != nil {
+		return err
+	}
+	_, err := git(dir, "commit", "-m", "Sync with "+tracking)
+	return err
+}
@@ -0,0 +1,162 @@
+package baccounts
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func setupGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "baccounts")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "baccounts")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+func TestGitSync(t *testing.T) {
+	setupGit(t)
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatal("git init --bare:", err, string(out))
+	}
+
+	fileA := filepath.Join(t.TempDir(), "baccounts.json")
+	pa := NewProfile("me", true)
+	pa.AddSite("a.com", "https://a.com", "me", "pass-a", "me@mac.com")
+	a := &Baccount{Profiles: []*Profile{pa}, Version: Version}
+	if err := a.GitInit(fileA, remote); err != nil {
+		t.Fatal("GitInit:", err)
+	}
+	if _, err := a.Sync(fileA, "origin", nil); err != nil {
+		t.Fatal("Sync a:", err)
+	}
+
+	fileB := filepath.Join(t.TempDir(), "baccounts.json")
+	pb := NewProfile("me", true)
+	pb.AddSite("b.com", "https://b.com", "me", "pass-b", "me@mac.com")
+	b := &Baccount{Profiles: []*Profile{pb}, Version: Version}
+	if err := b.GitInit(fileB, remote); err != nil {
+		t.Fatal("GitInit:", err)
+	}
+	summary, err := b.Sync(fileB, "origin", nil)
+	if err != nil {
+		t.Fatal("Sync b:", err)
+	}
+	if len(summary.Added) != 1 {
+		t.Error("a.com should be merged into b", summary)
+	}
+
+	if _, err := a.Sync(fileA, "origin", nil); err != nil {
+		t.Fatal("Sync a again:", err)
+	}
+	a2, err := LoadKeys(fileA)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	if _, ok := a2.Profiles[0].Sites["b.com"]; !ok {
+		t.Error("b.com should be synced back to a")
+	}
+
+	pa.AddSite("c.com", "https://c.com", "me", "pass-c", "me@mac.com")
+	a.SetMessage("Add c.com")
+	if err := a.UpdateConfigFile(fileA); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	msg, err := git(filepath.Dir(fileA), "log", "-1", "--format=%s")
+	if err != nil || msg != "Add c.com" {
+		t.Error("Unexpected commit message", msg, err)
+	}
+}
+
+func TestGitSyncAbort(t *testing.T) {
+	setupGit(t)
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatal("git init --bare:", err, string(out))
+	}
+	fileA := filepath.Join(t.TempDir(), "baccounts.json")
+	pa := NewProfile("me", true)
+	pa.AddSite("a.com", "https://a.com", "me", "pass-a", "me@mac.com")
+	a := &Baccount{Profiles: []*Profile{pa}, Version: Version}
+	if err := a.GitInit(fileA, remote); err != nil {
+		t.Fatal("GitInit:", err)
+	}
+	if _, err := a.Sync(fileA, "origin", nil); err != nil {
+		t.Fatal("Sync a:", err)
+	}
+
+	fileB := filepath.Join(t.TempDir(), "baccounts.json")
+	pb := NewProfile("me", true)
+	pb.AddSite("b.com", "https://b.com", "me", "pass-b", "me@mac.com")
+	b := &Baccount{Profiles: []*Profile{pb}, Version: Version}
+	if err := b.GitInit(fileB, remote); err != nil {
+		t.Fatal("GitInit:", err)
+	}
+	// Let the merge commit fail, after the datafile has been rewritten
+	hook := filepath.Join(filepath.Dir(fileB), ".git", "hooks", "commit-msg")
+	script := "#!/bin/sh\n! grep -q '^Sync with' \"$1\"\n"
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Sync(fileB, "origin", nil); err == nil {
+		t.Fatal("Sync should fail with the hook")
+	}
+	if _, err := git(filepath.Dir(fileB), "rev-parse", "--verify", "--quiet", "MERGE_HEAD"); err == nil {
+		t.Error("Merge should be aborted")
+	}
+
+	os.Remove(hook)
+	if _, err := b.Sync(fileB, "origin", nil); err != nil {
+		t.Error("Sync after the failure:", err)
+	}
+}
+
+func TestGitInitOwnDir(t *testing.T) {
+	setupGit(t)
+
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	if err := os.WriteFile(filepath.Join(filepath.Dir(datafile), "other.conf"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	b := &Baccount{Profiles: []*Profile{NewProfile("me", true)}, Version: Version}
+	if err := b.GitInit(datafile, ""); err == nil {
+		t.Error("GitInit should refuse a directory with other files")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(datafile), ".git")); err == nil {
+		t.Error("No repository should be made")
+	}
+}
+
+func TestGitCommitFailure(t *testing.T) {
+	setupGit(t)
+
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	p := NewProfile("me", true)
+	b := &Baccount{Profiles: []*Profile{p}, Version: Version}
+	if err := b.GitInit(datafile, ""); err != nil {
+		t.Fatal("GitInit:", err)
+	}
+	hook := filepath.Join(filepath.Dir(datafile), ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saved is saved, even if it isn't committed
+	p.AddSite("a.com", "https://a.com", "me", "pass-a", "me@mac.com")
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Error("UpdateConfigFile should only warn:", err)
+	}
+	b2, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	if _, ok := b2.Profiles[0].Sites["a.com"]; !ok {
+		t.Error("a.com should be saved")
+	}
+}
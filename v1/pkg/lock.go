@@ -0,0 +1,200 @@
+package baccounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockTimeout is how long Lock waits for another baccounts, e.g. one
+// waiting for a passphrase, to release the datafile.
+var LockTimeout = 10 * time.Second
+
+// Lock takes an advisory lock for the datafile, on a ".lock" file next to
+// it because saving replaces the datafile itself. LoadAccounts holds it
+// from load to exit so two load-modify-save cycles can't interleave.
+func Lock(datafile string) (*os.File, error) {
+	fp, err := os.OpenFile(datafile+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(LockTimeout)
+	for waiting := false; ; waiting = true {
+		locked, err := tryLockFile(fp)
+		if locked {
+			return fp, nil
+		}
+		if err == nil && time.Now().After(deadline) {
+			err = errors.New("another baccounts is still using it")
+		}
+		if err != nil {
+			fp.Close()
+			return nil, fmt.Errorf("Cannot lock %s: %v", fp.Name(), err)
+		}
+		if !waiting {
+			fmt.Fprintf(os.Stderr, "Waiting for another baccounts to release %s\n", fp.Name())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Unlock releases the lock taken by LoadAccounts. Long-running commands
+// call it after loading; later saves then lock only while writing.
+func (b *Baccount) Unlock() {
+	if b.lock == nil {
+		return
+	}
+	unlockFile(b.lock)
+	b.lock.Close()
+	b.lock = nil
+}
+
+func (b *Baccount) isDatafile(dest string) bool {
+	return b.path != "" && filepath.Clean(b.path) == filepath.Clean(dest)
+}
+
+// reconcile makes sure the datafile on disk is still the one we loaded.
+// If another writer saved it in between, its changes are taken into b
+// wherever b left things as loaded; when both changed the same thing,
+// saving fails instead of silently dropping one side.
+func (b *Baccount) reconcile(dest string) error {
+	data, err := os.ReadFile(dest)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if bytes.Equal(data, b.loaded) {
+		return nil
+	}
+
+	var base, disk Baccount
+	if err := json.Unmarshal(b.loaded, &base); err != nil {
+		return fmt.Errorf("%s changed on disk since loaded: re-run", dest)
+	}
+	if err := json.Unmarshal(data, &disk); err != nil {
+		return fmt.Errorf("%s changed on disk since loaded and is not parsable: %v", dest, err)
+	}
+	changes, err := b.diff3(&base, &disk)
+	if err != nil {
+		return fmt.Errorf("%s changed on disk since loaded, %v: re-run", dest, err)
+	}
+	fmt.Fprintf(os.Stderr, "%s changed on disk since loaded: taking its changes\n", dest)
+	for _, change := range changes {
+		change()
+	}
+	return nil
+}
+
+// diff3 compares b and disk with base, the datafile both started from,
+// and returns the changes to make to b to take those of disk.
+func (b *Baccount) diff3(base, disk *Baccount) ([]func(), error) {
+	var changes []func()
+	take := func(what string, ours, theirs, old interface{}, change func()) error {
+		switch {
+		case sameJSON(ours, theirs) || sameJSON(theirs, old):
+			return nil
+		case sameJSON(ours, old):
+			changes = append(changes, change)
+			return nil
+		}
+		return fmt.Errorf("%s was changed by both", what)
+	}
+
+	if err := take("default mail", b.DefaultMail, disk.DefaultMail, base.DefaultMail, func() {
+		b.DefaultMail = disk.DefaultMail
+	}); err != nil {
+		return nil, err
+	}
+	if err := take("recipients", b.Recipients, disk.Recipients, base.Recipients, func() {
+		b.Recipients = disk.Recipients
+	}); err != nil {
+		return nil, err
+	}
+	if err := take("git", b.Git, disk.Git, base.Git, func() {
+		b.Git = disk.Git
+	}); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, acc := range []*Baccount{base, b, disk} {
+		for _, p := range acc.Profiles {
+			names[p.Name] = true
+		}
+	}
+	for name := range names {
+		ours, theirs, old := b.findProfile(name), disk.findProfile(name), base.findProfile(name)
+		if ours == nil || theirs == nil {
+			if err := take("profile "+name, ours, theirs, old, func() {
+				b.setProfile(name, theirs)
+			}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := take("default of profile "+name, ours.Default, theirs.Default, old != nil && old.Default, func() {
+			ours.Default = theirs.Default
+		}); err != nil {
+			return nil, err
+		}
+		keys := make(map[string]bool)
+		for key := range ours.Sites {
+			keys[key] = true
+		}
+		for key := range theirs.Sites {
+			keys[key] = true
+		}
+		if old != nil {
+			for key := range old.Sites {
+				keys[key] = true
+			}
+		}
+		for key := range keys {
+			key, site := key, theirs.Sites[key]
+			var oldSite *Site
+			if old != nil {
+				oldSite = old.Sites[key]
+			}
+			if err := take(key+" in profile "+name, ours.Sites[key], site, oldSite, func() {
+				if site == nil {
+					delete(ours.Sites, key)
+				} else {
+					ours.Sites[key] = site
+				}
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// setProfile replaces, adds or, if p is nil, removes the profile name.
+func (b *Baccount) setProfile(name string, p *Profile) {
+	for i, q := range b.Profiles {
+		if q.Name != name {
+			continue
+		}
+		if p == nil {
+			b.Profiles = append(b.Profiles[:i], b.Profiles[i+1:]...)
+		} else {
+			b.Profiles[i] = p
+		}
+		return
+	}
+	if p != nil {
+		b.Profiles = append(b.Profiles, p)
+	}
+}
+
+func sameJSON(a, b interface{}) bool {
+	ja, erra := json.Marshal(a)
+	jb, errb := json.Marshal(b)
+	return erra == nil && errb == nil && bytes.Equal(ja, jb)
+}
@@ -0,0 +1,131 @@
+package baccounts
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConcurrentSave(t *testing.T) {
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+
+	p := NewProfile("me", true)
+	b := &Baccount{Profiles: []*Profile{p}, Version: Version}
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+
+	first, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	second, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+
+	first.Profiles[0].AddSite("a.com", "https://a.com", "me", "pass-a", "me@mac.com")
+	if err := first.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	second.Profiles[0].AddSite("b.com", "https://b.com", "me", "pass-b", "me@mac.com")
+	if err := second.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+
+	b2, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	for _, dom := range []string{"a.com", "b.com"} {
+		if _, ok := b2.Profiles[0].Sites[dom]; !ok {
+			t.Error("Lost a concurrent write:", dom)
+		}
+	}
+
+	// Same site changed at the same time can't be merged without asking
+	third, _ := LoadKeys(datafile)
+	fourth, _ := LoadKeys(datafile)
+	third.Profiles[0].Sites["a.com"].EncodedPass = "third"
+	fourth.Profiles[0].Sites["a.com"].EncodedPass = "fourth"
+	if err := third.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	if err := fourth.UpdateConfigFile(datafile); err == nil {
+		t.Error("Conflicting concurrent write should fail")
+	}
+}
+
+func TestConcurrentDelete(t *testing.T) {
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	p := NewProfile("me", true)
+	p.AddSite("a.com", "https://a.com", "me", "pass-a", "me@mac.com")
+	p.AddSite("b.com", "https://b.com", "me", "pass-b", "me@mac.com")
+	b := &Baccount{Profiles: []*Profile{p}, DefaultMail: "me@mac.com", Version: Version}
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+
+	first, _ := LoadKeys(datafile)
+	second, _ := LoadKeys(datafile)
+	delete(first.Profiles[0].Sites, "a.com")
+	first.DefaultMail = "you@mac.com"
+	if err := first.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	second.Profiles[0].Sites["b.com"].Name = "you"
+	second.Profiles = append(second.Profiles, NewProfile("work", false))
+	if err := second.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+
+	b2, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	if _, ok := b2.Profiles[0].Sites["a.com"]; ok {
+		t.Error("A concurrent delete came back")
+	}
+	if b2.DefaultMail != "you@mac.com" {
+		t.Error("Lost a concurrent default mail:", b2.DefaultMail)
+	}
+	if name := b2.Profiles[0].Sites["b.com"].Name; name != "you" {
+		t.Error("Lost a concurrent change:", name)
+	}
+	if len(b2.Profiles) != 2 {
+		t.Error("Lost the new profile:", len(b2.Profiles))
+	}
+
+	// Deleted on one side, changed on the other
+	third, _ := LoadKeys(datafile)
+	fourth, _ := LoadKeys(datafile)
+	delete(third.Profiles[0].Sites, "b.com")
+	if err := third.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	fourth.Profiles[0].Sites["b.com"].EncodedPass = "fourth"
+	if err := fourth.UpdateConfigFile(datafile); err == nil {
+		t.Error("Changing a site deleted meanwhile should fail")
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	first, err := Lock(datafile)
+	if err != nil {
+		t.Fatal("Lock:", err)
+	}
+
+	defer func(timeout time.Duration) { LockTimeout = timeout }(LockTimeout)
+	LockTimeout = 200 * time.Millisecond
+	if _, err := Lock(datafile); err == nil {
+		t.Error("Lock should give up while another one holds it")
+	}
+	unlockFile(first)
+	first.Close()
+	second, err := Lock(datafile)
+	if err != nil {
+		t.Fatal("Lock after unlocking:", err)
+	}
+	second.Close()
+}
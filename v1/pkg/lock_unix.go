@@ -0,0 +1,22 @@
+//go:build unix
+
+package baccounts
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes the lock unless someone else holds it, and tells
+// whether it did.
+func tryLockFile(fp *os.File) (bool, error) {
+	err := syscall.Flock(int(fp.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(fp *os.File) error {
+	return syscall.Flock(int(fp.Fd()), syscall.LOCK_UN)
+}
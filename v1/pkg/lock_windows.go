@@ -0,0 +1,24 @@
+//go:build windows
+
+package baccounts
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// tryLockFile takes the lock unless someone else holds it, and tells
+// whether it did.
+func tryLockFile(fp *os.File) (bool, error) {
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK | windows.LOCKFILE_FAIL_IMMEDIATELY)
+	err := windows.LockFileEx(windows.Handle(fp.Fd()), flags, 0, 1, 0, new(windows.Overlapped))
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(fp *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(fp.Fd()), 0, 1, 0, new(windows.Overlapped))
+}
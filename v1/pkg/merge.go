@@ -0,0 +1,165 @@
+package baccounts
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Conflict is a site that exists in both datafiles with different contents.
+type Conflict struct {
+	Profile string
+	Domain  string
+	Ours    *Site
+	Theirs  *Site
+}
+
+// Resolver is asked when modification times can't tell which side of a
+// conflict is newer. It returns true to take their site.
+type Resolver func(c *Conflict) (bool, error)
+
+// MergeSummary lists what Merge changed, one human-readable line each.
+type MergeSummary struct {
+	Added   []string
+	Updated []string
+	Kept    []string
+}
+
+func (s *MergeSummary) Changed() bool {
+	return len(s.Added) > 0 || len(s.Updated) > 0
+}
+
+func (s *MergeSummary) Print() {
+	for _, line := range s.Added {
+		fmt.Println("added:  ", line)
+	}
+	for _, line := range s.Updated {
+		fmt.Println("updated:", line)
+	}
+	for _, line := range s.Kept {
+		fmt.Println("kept:   ", line)
+	}
+	fmt.Printf("%d added, %d updated, %d kept\n", len(s.Added), len(s.Updated), len(s.Kept))
+}
+
+// Merge brings profiles and sites of other into b. Profiles are matched by
+// name and sites by domain and mail; anything missing on our side is added. When
+// both sides have a site that differs, the newer one by Site.Modified
+// wins, and resolve is asked if that doesn't decide it. A site whose key
+// is taken by another mail on our side is always left to resolve.
+func (b *Baccount) Merge(other *Baccount, resolve Resolver) (*MergeSummary, error) {
+	summary := &MergeSummary{}
+	for _, theirs := range other.Profiles {
+		ours := b.findProfile(theirs.Name)
+		if ours == nil {
+			ours = NewProfile(theirs.Name, false)
+			b.Profiles = append(b.Profiles, ours)
+			summary.Added = append(summary.Added, "profile "+theirs.Name)
+		}
+		if ours.Sites == nil {
+			ours.Sites = make(map[string]*Site)
+		}
+
+		keys := make([]string, 0, len(theirs.Sites))
+		for key := range theirs.Sites {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			their := theirs.Sites[key]
+			// Accounts are told apart by domain and mail, as the keys
+			// of a domain's accounts depend on the order they were added
+			dom, our := ours.findAccount(SiteDomain(key), their.Mail)
+			where := fmt.Sprintf("%s @ %s", key, theirs.Name)
+			remailed := false
+			if our == nil {
+				if _, taken := ours.Sites[key]; !taken {
+					ours.Sites[key] = copySite(their)
+					summary.Added = append(summary.Added, where)
+					continue
+				}
+				// The key is an account with another mail: its mail
+				// changed on one side, or it's another account, which
+				// Modified can't tell apart
+				dom, our, remailed = key, ours.Sites[key], true
+			}
+			if sameSite(our, their) {
+				continue
+			}
+
+			takeTheirs := false
+			switch {
+			case !remailed && our.Modified.Before(their.Modified):
+				takeTheirs = true
+			case !remailed && our.Modified.After(their.Modified):
+				takeTheirs = false
+			case resolve == nil && remailed:
+				return summary, fmt.Errorf("Conflict on %s, with mail %s here and %s there, and no way to resolve it", where, our.Mail, their.Mail)
+			case resolve == nil:
+				return summary, fmt.Errorf("Conflict on %s and no way to resolve it", where)
+			default:
+				t, err := resolve(&Conflict{theirs.Name, dom, our, their})
+				if err != nil {
+					return summary, err
+				}
+				takeTheirs = t
+			}
+
+			if takeTheirs {
+				ours.Sites[dom] = copySite(their)
+				summary.Updated = append(summary.Updated, where)
+			} else {
+				summary.Kept = append(summary.Kept, where)
+			}
+		}
+	}
+	return summary, nil
+}
+
+func (b *Baccount) findProfile(name string) *Profile {
+	for _, p := range b.Profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (p *Profile) findAccount(domain, mail string) (string, *Site) {
+	for key, site := range p.Sites {
+		if SiteDomain(key) == domain && site.Mail == mail {
+			return key, site
+		}
+	}
+	return "", nil
+}
+
+func sameSite(a, b *Site) bool {
+	x, y := *a, *b
+	x.Modified, y.Modified = time.Time{}, time.Time{}
+	x.PassModified, y.PassModified = time.Time{}, time.Time{}
+	return reflect.DeepEqual(x, y)
+}
+
+func copySite(s *Site) *Site {
+	c := *s
+	if s.OTP != nil {
+		otp := *s.OTP
+		c.OTP = &otp
+	}
+	if s.SSHKey != nil {
+		key := *s.SSHKey
+		c.SSHKey = &key
+	}
+	c.Tags = append([]string(nil), s.Tags...)
+	if s.Fields != nil {
+		c.Fields = make(map[string]*Field, len(s.Fields))
+		for name, field := range s.Fields {
+			f := *field
+			c.Fields[name] = &f
+		}
+	}
+	return &c
+}
@@ -0,0 +1,106 @@
+package baccounts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+
+	ours := NewProfile("me@mac.com", true)
+	ours.AddSite("a.com", "https://a.com", "me@mac.com", "pass-a", "me@mac.com")
+	ours.AddSite("b.com", "https://b.com", "me@mac.com", "pass-b", "me@mac.com")
+	ours.Sites["b.com"].Modified = old
+	b := &Baccount{Profiles: []*Profile{ours}}
+
+	theirs := NewProfile("me@mac.com", true)
+	theirs.AddSite("a.com", "https://a.com", "me@mac.com", "pass-a", "me@mac.com")
+	theirs.AddSite("b.com", "https://b.com", "me@mac.com", "pass-b2", "me@mac.com")
+	theirs.AddSite("c.com", "https://c.com", "me@mac.com", "pass-c", "me@mac.com")
+	work := NewProfile("work", false)
+	work.AddSite("d.com", "https://d.com", "work", "pass-d", "me@work.com")
+	other := &Baccount{Profiles: []*Profile{theirs, work}}
+
+	summary, err := b.Merge(other, nil)
+	if err != nil {
+		t.Fatal("Merge:", err)
+	}
+	if len(summary.Added) != 3 || len(summary.Updated) != 1 || len(summary.Kept) != 0 {
+		t.Error("Unexpected summary", summary)
+	}
+	if ours.Sites["b.com"].EncodedPass != "pass-b2" {
+		t.Error("Newer site should win", ours.Sites["b.com"])
+	}
+	if _, ok := ours.Sites["c.com"]; !ok {
+		t.Error("c.com not added")
+	}
+	if p := b.findProfile("work"); p == nil || p.Default {
+		t.Error("work profile not added as non-default", p)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	now := time.Now()
+
+	ours := NewProfile("me", true)
+	ours.AddSite("a.com", "https://a.com", "me", "ours", "me@mac.com")
+	ours.Sites["a.com"].Modified = now
+	b := &Baccount{Profiles: []*Profile{ours}}
+
+	theirs := NewProfile("me", true)
+	theirs.AddSite("a.com", "https://a.com", "me", "theirs", "me@mac.com")
+	theirs.Sites["a.com"].Modified = now
+	other := &Baccount{Profiles: []*Profile{theirs}}
+
+	if _, err := b.Merge(other, nil); err == nil {
+		t.Error("Conflict without resolver should fail")
+	}
+
+	asked := 0
+	summary, err := b.Merge(other, func(c *Conflict) (bool, error) {
+		asked++
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal("Merge:", err)
+	}
+	if asked != 1 || len(summary.Kept) != 1 || summary.Changed() {
+		t.Error("Unexpected resolution", asked, summary)
+	}
+	if ours.Sites["a.com"].EncodedPass != "ours" {
+		t.Error("Ours should be kept")
+	}
+}
+
+func TestMergeMailChanged(t *testing.T) {
+	ours := NewProfile("me", true)
+	ours.AddSite("a.com", "https://a.com", "me", "ours", "old@mac.com")
+	ours.AddSite("a.com", "https://a.com", "me", "other", "other@mac.com")
+	b := &Baccount{Profiles: []*Profile{ours}}
+
+	// Their a.com has a new mail, and so does the account keyed by mail
+	theirs := NewProfile("me", true)
+	theirs.AddSite("a.com", "https://a.com", "me", "theirs", "new@mac.com")
+	theirs.Sites[SiteKey("a.com", "other@mac.com")] = &Site{Url: "https://a.com", EncodedPass: "moved", Mail: "moved@mac.com"}
+	theirs.Sites["a.com"].Touch()
+	other := &Baccount{Profiles: []*Profile{theirs}}
+
+	if _, err := b.Merge(other, nil); err == nil {
+		t.Error("Changed mail without resolver should fail")
+	}
+	asked := 0
+	summary, err := b.Merge(other, func(c *Conflict) (bool, error) {
+		asked++
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal("Merge:", err)
+	}
+	if asked != 2 || summary.Changed() || len(ours.Sites) != 2 {
+		t.Error("Unexpected resolution", asked, summary, ours.Sites)
+	}
+	if ours.Sites[SiteKey("a.com", "other@mac.com")].EncodedPass != "other" {
+		t.Error("Account keyed by mail overwritten")
+	}
+}
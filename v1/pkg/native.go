@@ -0,0 +1,196 @@
+package baccounts
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Browsers talk to native messaging hosts, like native-host, by messages
+// of JSON prefixed with their length as a 32-bit integer in native byte
+// order, which is little endian on every platform browsers support.
+
+// Browsers accept at most 1 MiB from a host and send at most 64 MiB
+const (
+	maxNativeReply   = 1 << 20
+	maxNativeRequest = 64 << 20
+)
+
+// NativeRequest is a message of the browser extension. Type is "ping" or
+// "credentials", which asks for the logins of the Origin of a page.
+type NativeRequest struct {
+	Type   string
+	Origin string
+}
+
+type NativeResponse struct {
+	Type        string
+	Origin      string
+	Version     string // Of ping
+	Credentials []NativeCredential
+	Error       string // Empty on success
+}
+
+type NativeCredential struct {
+	Profile  string
+	Key      string
+	Username string
+	Password string
+}
+
+func ReadNativeMessage(r io.Reader, v interface{}) error {
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return err
+	}
+	if n > maxNativeRequest {
+		return fmt.Errorf("Message too long: %d bytes", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func WriteNativeMessage(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxNativeReply {
+		return fmt.Errorf("Message too long: %d bytes", len(data))
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// NativeHost answers a browser extension with logins of the sites of a
+// page, once Confirm agrees to each answer with passwords.
+type NativeHost struct {
+	b       *Baccount
+	coder   *Coder
+	Unlock  func(coder *Coder) error // Sets the passphrase, before the first password is decrypted
+	Confirm func(question string) bool
+
+	unlocked bool
+}
+
+func NewNativeHost(b *Baccount, coder *Coder) *NativeHost {
+	return &NativeHost{b: b, coder: coder}
+}
+
+// Serve answers requests from r on w until r ends.
+func (h *NativeHost) Serve(r io.Reader, w io.Writer) error {
+	for {
+		var req NativeRequest
+		err := ReadNativeMessage(r, &req)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var resp *NativeResponse
+		var syntax *json.SyntaxError
+		var typ *json.UnmarshalTypeError
+		if errors.As(err, &syntax) || errors.As(err, &typ) {
+			// The next message is still where it should be
+			resp = &NativeResponse{Error: "Invalid message: " + err.Error()}
+		} else if err != nil {
+			return err
+		} else {
+			resp = h.answer(&req)
+		}
+		if err := WriteNativeMessage(w, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (h *NativeHost) answer(req *NativeRequest) *NativeResponse {
+	resp := &NativeResponse{Type: req.Type, Origin: req.Origin}
+	switch req.Type {
+	case "ping":
+		resp.Version = Version
+	case "credentials":
+		creds, err := h.credentials(req.Origin)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Credentials = creds
+	default:
+		resp.Error = "Unknown type: " + req.Type
+	}
+	return resp
+}
+
+func (h *NativeHost) credentials(origin string) ([]NativeCredential, error) {
+	// The browser keeps the host running while others save
+	if err := h.b.Reload(); err != nil {
+		return nil, err
+	}
+	creds := h.b.OriginCredentials(origin)
+	if len(creds) == 0 {
+		return creds, nil
+	}
+	accounts := make([]string, len(creds))
+	for i, c := range creds {
+		accounts[i] = fmt.Sprintf("%s (%s @ %s)", c.Username, c.Key, c.Profile)
+	}
+	question := fmt.Sprintf("Give the browser the password for %s of %s?", origin, strings.Join(accounts, ", "))
+	if h.Confirm == nil || !h.Confirm(question) {
+		return nil, fmt.Errorf("Denied")
+	}
+
+	if !h.unlocked && h.Unlock != nil {
+		if err := h.Unlock(h.coder); err != nil {
+			return nil, err
+		}
+	}
+	for i := range creds {
+		site := h.b.findProfile(creds[i].Profile).Sites[creds[i].Key]
+		pass, err := h.coder.Decode(site.EncodedPass)
+		if err != nil {
+			return nil, err
+		}
+		creds[i].Password = pass
+	}
+	h.unlocked = true
+	return creds, nil
+}
+
+// OriginCredentials lists the logins of all profiles for a page of the
+// origin, passwords left empty. The host of Site.Url has to be that of
+// the origin, and a site of https is never given to a page of http.
+func (b *Baccount) OriginCredentials(origin string) []NativeCredential {
+	creds := make([]NativeCredential, 0)
+	o, err := url.Parse(origin)
+	if err != nil || o.Host == "" {
+		return creds
+	}
+	for _, p := range b.Profiles {
+		for key, site := range p.Sites {
+			u, err := url.Parse(site.Url)
+			if err != nil || !strings.EqualFold(u.Host, o.Host) {
+				continue
+			}
+			if u.Scheme == "https" && o.Scheme != "https" {
+				continue
+			}
+			creds = append(creds, NativeCredential{Profile: p.Name, Key: key, Username: site.Username()})
+		}
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		if creds[i].Profile != creds[j].Profile {
+			return creds[i].Profile < creds[j].Profile
+		}
+		return creds[i].Key < creds[j].Key
+	})
+	return creds
+}
@@ -0,0 +1,148 @@
+package baccounts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// fakeBrowser runs a host on pipes and talks to it like a browser does.
+type fakeBrowser struct {
+	t    *testing.T
+	in   *io.PipeWriter
+	out  *io.PipeReader
+	done chan error
+}
+
+func newFakeBrowser(t *testing.T, h *NativeHost) *fakeBrowser {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	f := &fakeBrowser{t, inW, outR, make(chan error, 1)}
+	go func() {
+		err := h.Serve(inR, outW)
+		outW.Close()
+		f.done <- err
+	}()
+	return f
+}
+
+func (f *fakeBrowser) send(raw []byte) *NativeResponse {
+	binary.Write(f.in, binary.LittleEndian, uint32(len(raw)))
+	f.in.Write(raw)
+	var resp NativeResponse
+	if err := ReadNativeMessage(f.out, &resp); err != nil {
+		f.t.Fatal("ReadNativeMessage:", err)
+	}
+	return &resp
+}
+
+func (f *fakeBrowser) close() error {
+	f.in.Close()
+	return <-f.done
+}
+
+func TestNativeMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteNativeMessage(&buf, &NativeRequest{"ping", ""}); err != nil {
+		t.Fatal("WriteNativeMessage:", err)
+	}
+	if !bytes.Equal(buf.Bytes()[:4], []byte{27, 0, 0, 0}) {
+		t.Error("Unexpected length prefix", buf.Bytes())
+	}
+	var req NativeRequest
+	if err := ReadNativeMessage(&buf, &req); err != nil || req.Type != "ping" {
+		t.Error("Unexpected message", req, err)
+	}
+	if err := ReadNativeMessage(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), &req); err == nil {
+		t.Error("Too long message should fail")
+	}
+}
+
+func TestNativeHost(t *testing.T) {
+	coder := NewTestCoder()
+	encpass, err := coder.Encode("gh-pass", 0)
+	if err != nil {
+		t.Fatal("Encode:", err)
+	}
+	me := NewProfile("me", true)
+	me.AddSite("github.com", "https://github.com/login", "alice", encpass, "alice@mac.com")
+	me.AddSite("gist.github.com", "https://gist.github.com", "alice", encpass, "alice@mac.com")
+	work := NewProfile("work", false)
+	work.AddSite("github.com", "https://github.com", "bob", encpass, "bob@work.com")
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	b := &Baccount{Profiles: []*Profile{me, work}, Version: Version}
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	b, err = LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+
+	h := NewNativeHost(b, coder)
+	confirmed, unlocked := 0, 0
+	answer := false
+	h.Confirm = func(question string) bool {
+		confirmed++
+		return answer
+	}
+	h.Unlock = func(*Coder) error {
+		unlocked++
+		return nil
+	}
+	f := newFakeBrowser(t, h)
+
+	if resp := f.send([]byte(`{"Type": "ping"}`)); resp.Version != Version || resp.Error != "" {
+		t.Error("Unexpected pong", resp)
+	}
+	if resp := f.send([]byte(`{"Type": "credentials", "Origin": "https://github.com"}`)); resp.Error != "Denied" || len(resp.Credentials) != 0 {
+		t.Error("Passwords given without confirmation", resp)
+	}
+
+	answer = true
+	resp := f.send([]byte(`{"Type": "credentials", "Origin": "https://github.com"}`))
+	if resp.Error != "" || len(resp.Credentials) != 2 {
+		t.Fatal("Unexpected credentials", resp)
+	}
+	if c := resp.Credentials[0]; c.Profile != "me" || c.Username != "alice@mac.com" || c.Password != "gh-pass" {
+		t.Error("Unexpected credential", c)
+	}
+	if c := resp.Credentials[1]; c.Profile != "work" || c.Username != "bob@work.com" || c.Password != "gh-pass" {
+		t.Error("Unexpected credential", c)
+	}
+	f.send([]byte(`{"Type": "credentials", "Origin": "https://gist.github.com"}`))
+
+	for _, origin := range []string{"http://github.com", "https://evil-github.com", "https://github.com.evil.com", "garbage"} {
+		if resp := f.send([]byte(`{"Type": "credentials", "Origin": "` + origin + `"}`)); len(resp.Credentials) != 0 {
+			t.Error("Credentials given to", origin, resp)
+		}
+	}
+	// Another process saves while the browser keeps the host running
+	other, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	other.Profiles[0].AddSite("example.com", "https://example.com", "alice", encpass, "alice@mac.com")
+	if err := other.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	if resp := f.send([]byte(`{"Type": "credentials", "Origin": "https://example.com"}`)); len(resp.Credentials) != 1 || resp.Credentials[0].Password != "gh-pass" {
+		t.Error("Site saved meanwhile not offered", resp)
+	}
+
+	if resp := f.send([]byte(`{"Type": `)); resp.Error == "" {
+		t.Error("Invalid message should be an error", resp)
+	}
+	if resp := f.send([]byte(`{"Type": "nosuch"}`)); resp.Error == "" {
+		t.Error("Unknown type should be an error", resp)
+	}
+
+	if err := f.close(); err != nil {
+		t.Error("Serve:", err)
+	}
+	if confirmed != 4 || unlocked != 1 {
+		t.Error("Unexpected confirmations or unlocks", confirmed, unlocked)
+	}
+}
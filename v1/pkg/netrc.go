@@ -0,0 +1,247 @@
+package baccounts
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// NetrcTag marks sites to write to ~/.netrc.
+const NetrcTag = "netrc"
+
+// The lines around the stanzas baccounts writes in ~/.netrc; anything
+// outside is left as it was.
+const (
+	netrcBegin = "# BEGIN baccounts: managed by 'baccounts netrc', edits are overwritten"
+	netrcEnd   = "# END baccounts"
+)
+
+// NetrcEntry is a machine stanza of ~/.netrc.
+type NetrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+// NetrcEntries decrypts the sites tagged tag of all profiles with coder,
+// with the host of Site.Url as machine and Site.Mail, or else the name,
+// as login. A host is only taken once, as tools use the first anyway.
+func (b *Baccount) NetrcEntries(coder *Coder, tag string) ([]NetrcEntry, error) {
+	entries := make([]NetrcEntry, 0)
+	seen := make(map[string]bool)
+	for _, p := range b.Profiles {
+		keys := make([]string, 0, len(p.Sites))
+		for key := range p.Sites {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			site := p.Sites[key]
+			if !site.HasTag(tag) {
+				continue
+			}
+			u, err := url.Parse(site.Url)
+			if err != nil || u.Hostname() == "" {
+				return nil, fmt.Errorf("No host in the URL of %s @ %s: %s", key, p.Name, site.Url)
+			}
+			if seen[u.Hostname()] {
+				continue
+			}
+			pass, err := coder.Decode(site.EncodedPass)
+			if err != nil {
+				return nil, err
+			}
+			login := site.Mail
+			if login == "" {
+				login = site.Name
+			}
+			entries = append(entries, NetrcEntry{u.Hostname(), login, pass})
+			seen[u.Hostname()] = true
+		}
+	}
+	return entries, nil
+}
+
+// NetrcBlock is the managed block of the entries, markers included.
+func NetrcBlock(entries []NetrcEntry) string {
+	var b strings.Builder
+	b.WriteString(netrcBegin + "\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "machine %s\n  login %s\n  password %s\n", netrcToken(e.Machine), netrcToken(e.Login), netrcToken(e.Password))
+	}
+	b.WriteString(netrcEnd + "\n")
+	return b.String()
+}
+
+// ReplaceNetrcBlock puts block in place of the managed block of the
+// netrc, or at its end if it has none yet. The block always comes before
+// a default entry, which has to be the last one.
+func ReplaceNetrcBlock(netrc, block string) string {
+	before, _, after, ok := splitNetrc(netrc)
+	if ok {
+		if netrcDefault(before) < 0 {
+			return before + block + after
+		}
+		netrc = before + after
+	}
+	if i := netrcDefault(netrc); i >= 0 {
+		return netrc[:i] + block + netrc[i:]
+	}
+	if netrc != "" && !strings.HasSuffix(netrc, "\n") {
+		netrc += "\n"
+	}
+	return netrc + block
+}
+
+// netrcDefault is where the line of the default entry of the netrc
+// starts, or -1 without one.
+func netrcDefault(netrc string) int {
+	offset := 0
+	previous := ""
+	for _, line := range strings.SplitAfter(netrc, "\n") {
+		for _, tok := range netrcTokens(line) {
+			switch previous {
+			case "machine", "login", "password", "account", "macdef":
+				// tok is a value, even if it reads "default"
+			default:
+				if tok == "default" {
+					return offset
+				}
+			}
+			previous = tok
+		}
+		offset += len(line)
+	}
+	return -1
+}
+
+// NetrcDrift tells how the managed block of the netrc differs from the
+// entries, one line per machine, without telling passwords.
+func NetrcDrift(netrc string, entries []NetrcEntry) []string {
+	_, block, _, ok := splitNetrc(netrc)
+	if !ok {
+		return []string{"no managed block"}
+	}
+	current := make(map[string]NetrcEntry)
+	for _, e := range parseNetrc(block) {
+		current[e.Machine] = e
+	}
+
+	drift := make([]string, 0)
+	for _, e := range entries {
+		c, ok := current[e.Machine]
+		switch {
+		case !ok:
+			drift = append(drift, "missing: "+e.Machine)
+		case c.Login != e.Login:
+			drift = append(drift, fmt.Sprintf("login changed: %s (%s, not %s)", e.Machine, e.Login, c.Login))
+		case c.Password != e.Password:
+			drift = append(drift, "password changed: "+e.Machine)
+		}
+		delete(current, e.Machine)
+	}
+	extra := make([]string, 0, len(current))
+	for machine := range current {
+		extra = append(extra, "not in vault: "+machine)
+	}
+	sort.Strings(extra)
+	return append(drift, extra...)
+}
+
+// splitNetrc splits the netrc around its managed block, which comes
+// with its markers.
+func splitNetrc(netrc string) (before, block, after string, ok bool) {
+	i := strings.Index(netrc, netrcBegin+"\n")
+	if i < 0 {
+		return netrc, "", "", false
+	}
+	j := strings.Index(netrc[i:], netrcEnd+"\n")
+	if j < 0 {
+		return netrc, "", "", false
+	}
+	j += i + len(netrcEnd) + 1
+	return netrc[:i], netrc[i:j], netrc[j:], true
+}
+
+// parseNetrc reads the machine stanzas of a netrc, as far as baccounts
+// writes them.
+func parseNetrc(netrc string) []NetrcEntry {
+	var entries []NetrcEntry
+	tokens := netrcTokens(netrc)
+	for i := 0; i+1 < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			entries = append(entries, NetrcEntry{Machine: tokens[i+1]})
+		case "login":
+			if len(entries) > 0 {
+				entries[len(entries)-1].Login = tokens[i+1]
+			}
+		case "password":
+			if len(entries) > 0 {
+				entries[len(entries)-1].Password = tokens[i+1]
+			}
+		default:
+			continue
+		}
+		i++
+	}
+	return entries
+}
+
+// netrcToken quotes s if it has to be, the way curl reads it.
+func netrcToken(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\"\\#") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func netrcTokens(netrc string) []string {
+	var tokens []string
+	for _, line := range strings.Split(netrc, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for len(line) > 0 {
+			line = strings.TrimLeft(line, " \t")
+			if line == "" {
+				break
+			}
+			if line[0] != '"' {
+				end := strings.IndexAny(line, " \t")
+				if end < 0 {
+					end = len(line)
+				}
+				tokens = append(tokens, line[:end])
+				line = line[end:]
+				continue
+			}
+			var tok strings.Builder
+			i := 1
+			for ; i < len(line) && line[i] != '"'; i++ {
+				c := line[i]
+				if c == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						c = '\n'
+					case 't':
+						c = '\t'
+					default:
+						c = line[i]
+					}
+				}
+				tok.WriteByte(c)
+			}
+			tokens = append(tokens, tok.String())
+			if i < len(line) {
+				i++ // The closing quote
+			}
+			line = line[i:]
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,88 @@
+package baccounts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNetrcEntries(t *testing.T) {
+	coder := NewTestCoder()
+	encode := func(pass string) string {
+		enc, err := coder.Encode(pass, 0)
+		if err != nil {
+			t.Fatal("Encode:", err)
+		}
+		return enc
+	}
+	me := NewProfile("me", true)
+	me.AddSite("api.example.com", "https://api.example.com:8443/v1", "me", encode("pass a"), "me@mac.com")
+	me.AddSite("github.com", "https://github.com", "me", encode("pass-b"), "")
+	me.AddSite("bank.com", "https://bank.com", "me", encode("pass-c"), "me@mac.com")
+	work := NewProfile("work", false)
+	work.AddSite("github.com", "https://github.com", "work", encode("pass-d"), "me@work.com")
+	for _, site := range []*Site{me.Sites["api.example.com"], me.Sites["github.com"], work.Sites["github.com"]} {
+		site.AddTags(NetrcTag)
+	}
+	b := &Baccount{Profiles: []*Profile{me, work}}
+
+	entries, err := b.NetrcEntries(coder, NetrcTag)
+	if err != nil {
+		t.Fatal("NetrcEntries:", err)
+	}
+	expected := []NetrcEntry{{"api.example.com", "me@mac.com", "pass a"}, {"github.com", "me", "pass-b"}}
+	if len(entries) != len(expected) || entries[0] != expected[0] || entries[1] != expected[1] {
+		t.Error("Unexpected entries", entries)
+	}
+}
+
+func TestNetrcBlock(t *testing.T) {
+	entries := []NetrcEntry{{"a.com", "me", `pa "ss`}, {"b.com", "me", "pass-b"}}
+	handWritten := "machine old.com login me password x\n"
+
+	netrc := ReplaceNetrcBlock(handWritten, NetrcBlock(entries))
+	if !strings.HasPrefix(netrc, handWritten) || !strings.Contains(netrc, `password "pa \"ss"`) {
+		t.Error("Unexpected netrc", netrc)
+	}
+	if drift := NetrcDrift(netrc, entries); len(drift) != 0 {
+		t.Error("Unexpected drift", drift)
+	}
+
+	netrc = ReplaceNetrcBlock(netrc+"default login anonymous\n", NetrcBlock(entries[1:]))
+	if !strings.HasPrefix(netrc, handWritten) || !strings.HasSuffix(netrc, "default login anonymous\n") || strings.Contains(netrc, "a.com") {
+		t.Error("Hand-written stanzas should be kept", netrc)
+	}
+
+	changed := []NetrcEntry{{"b.com", "me", "new"}, {"c.com", "you", "pass-c"}}
+	drift := NetrcDrift(netrc, changed)
+	if strings.Join(drift, ";") != "password changed: b.com;missing: c.com" {
+		t.Error("Unexpected drift", drift)
+	}
+	drift = NetrcDrift(netrc, nil)
+	if strings.Join(drift, ";") != "not in vault: b.com" {
+		t.Error("Unexpected drift", drift)
+	}
+	if drift := NetrcDrift(handWritten, entries); len(drift) != 1 {
+		t.Error("Missing block should be drift", drift)
+	}
+}
+
+func TestNetrcBlockBeforeDefault(t *testing.T) {
+	entries := []NetrcEntry{{"a.com", "me", "pass-a"}}
+	handWritten := "machine old.com login default password x\n# default is the last\ndefault\n  login anonymous password me@mac.com\n"
+
+	netrc := ReplaceNetrcBlock(handWritten, NetrcBlock(entries))
+	expected := "machine old.com login default password x\n# default is the last\n" + NetrcBlock(entries) +
+		"default\n  login anonymous password me@mac.com\n"
+	if netrc != expected {
+		t.Error("Block should come before default", netrc)
+	}
+	if again := ReplaceNetrcBlock(netrc, NetrcBlock(entries)); again != netrc {
+		t.Error("Replacing should keep the block in place", again)
+	}
+
+	// A block written after default is moved before it
+	netrc = ReplaceNetrcBlock(handWritten+NetrcBlock(entries), NetrcBlock(entries))
+	if netrc != expected {
+		t.Error("Block after default should be moved", netrc)
+	}
+}
@@ -0,0 +1,182 @@
+package baccounts
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTP generates one-time passwords of a site, as described by an
+// otpauth:// URI: TOTP (RFC 6238) or HOTP (RFC 4226).
+type OTP struct {
+	Type          string // "totp" or "hotp"
+	EncodedSecret string // Base32 secret, encrypted by the Coder
+	Algorithm     string // SHA1, SHA256 or SHA512
+	Digits        int
+	Period        int    // Seconds a TOTP code is valid
+	Counter       uint64 // Next HOTP counter
+}
+
+// NewOTP returns a TOTP with the defaults most sites use.
+func NewOTP() *OTP {
+	return &OTP{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30}
+}
+
+// ParseOTPURI reads an otpauth:// URI, as in QR codes of 2FA setups. It
+// returns the OTP without secret, and the secret.
+func ParseOTPURI(uri string) (*OTP, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "otpauth" {
+		return nil, "", fmt.Errorf("Not an otpauth:// URI: %s", uri)
+	}
+
+	o := NewOTP()
+	o.Type = strings.ToLower(u.Host)
+	q := u.Query()
+	if alg := q.Get("algorithm"); alg != "" {
+		o.Algorithm = strings.ToUpper(alg)
+	}
+	if digits := q.Get("digits"); digits != "" {
+		if o.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, "", fmt.Errorf("Bad digits: %s", digits)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		if o.Period, err = strconv.Atoi(period); err != nil {
+			return nil, "", fmt.Errorf("Bad period: %s", period)
+		}
+	}
+	if counter := q.Get("counter"); counter != "" {
+		if o.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return nil, "", fmt.Errorf("Bad counter: %s", counter)
+		}
+	}
+
+	secret := q.Get("secret")
+	if secret == "" {
+		return nil, "", fmt.Errorf("No secret in the URI")
+	}
+	if err := o.Validate(secret); err != nil {
+		return nil, "", err
+	}
+	return o, secret, nil
+}
+
+// Validate checks the parameters and that secret can generate codes.
+func (o *OTP) Validate(secret string) error {
+	if o.Type != "totp" && o.Type != "hotp" {
+		return fmt.Errorf("Unknown OTP type: %s", o.Type)
+	}
+	if _, err := o.hash(); err != nil {
+		return err
+	}
+	if o.Digits < 6 || o.Digits > 10 {
+		return fmt.Errorf("Digits should be 6 to 10: %d", o.Digits)
+	}
+	if o.Type == "totp" && o.Period <= 0 {
+		return fmt.Errorf("Period should be positive: %d", o.Period)
+	}
+	_, err := decodeOTPSecret(secret)
+	return err
+}
+
+func (o *OTP) hash() (func() hash.Hash, error) {
+	switch o.Algorithm {
+	case "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("Unknown OTP algorithm: %s", o.Algorithm)
+}
+
+func decodeOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("OTP secret is not base32: %v", err)
+	}
+	return key, nil
+}
+
+// Code computes the code at t for TOTP, or for the current counter for
+// HOTP; callers then Advance a HOTP and save it.
+func (o *OTP) Code(secret string, t time.Time) (string, error) {
+	key, err := decodeOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	h, err := o.hash()
+	if err != nil {
+		return "", err
+	}
+
+	counter := o.Counter
+	if o.Type == "totp" {
+		counter = uint64(t.Unix()) / uint64(o.Period)
+	}
+	return hotp(h, key, counter, o.Digits), nil
+}
+
+// Remaining tells how long the TOTP code at t stays valid.
+func (o *OTP) Remaining(t time.Time) time.Duration {
+	period := int64(o.Period)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// Advance moves a HOTP to its next counter.
+func (o *OTP) Advance() {
+	o.Counter++
+}
+
+func hotp(h func() hash.Hash, key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(h, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	mod := uint64(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// OTPCode decrypts the OTP secret of the site and computes its code at t.
+// A HOTP is advanced to the next counter, so the site needs saving then.
+func (site *Site) OTPCode(coder *Coder, t time.Time) (string, error) {
+	if site.OTP == nil {
+		return "", fmt.Errorf("No OTP for %s", site.Url)
+	}
+	secret, err := coder.Decode(site.OTP.EncodedSecret)
+	if err != nil {
+		return "", err
+	}
+	code, err := site.OTP.Code(secret, t)
+	if err != nil {
+		return "", err
+	}
+	if site.OTP.Type == "hotp" {
+		site.OTP.Advance()
+		site.Touch()
+	}
+	return code, nil
+}
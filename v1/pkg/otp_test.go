@@ -0,0 +1,83 @@
+package baccounts
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// Test vectors of RFC 6238, Appendix B
+	secrets := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	cases := []struct {
+		unix int64
+		alg  string
+		code string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1234567890, "SHA256", "91819424"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, c := range cases {
+		o := NewOTP()
+		o.Algorithm = c.alg
+		o.Digits = 8
+		secret := base32.StdEncoding.EncodeToString([]byte(secrets[c.alg]))
+		code, err := o.Code(secret, time.Unix(c.unix, 0))
+		if err != nil || code != c.code {
+			t.Error("Unexpected code", c, code, err)
+		}
+	}
+}
+
+func TestHOTP(t *testing.T) {
+	coder := NewTestCoder()
+
+	// Test vectors of RFC 4226, Appendix D
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	o, _, err := ParseOTPURI("otpauth://hotp/Example:me@example.com?secret=" + secret + "&counter=0&issuer=Example")
+	if err != nil {
+		t.Fatal("ParseOTPURI:", err)
+	}
+	if o.EncodedSecret, err = coder.Encode(secret, 0); err != nil {
+		t.Fatal("Encode:", err)
+	}
+	site := &Site{Url: "https://example.com", OTP: o}
+
+	for _, expected := range []string{"755224", "287082", "359152"} {
+		code, err := site.OTPCode(coder, time.Now())
+		if err != nil || code != expected {
+			t.Error("Unexpected code", code, expected, err)
+		}
+	}
+	if o.Counter != 3 {
+		t.Error("Counter not advanced", o.Counter)
+	}
+}
+
+func TestParseOTPURI(t *testing.T) {
+	o, secret, err := ParseOTPURI("otpauth://totp/GitHub:me?secret=JBSWY3DPEHPK3PXP&algorithm=sha256&digits=8&period=60")
+	if err != nil {
+		t.Fatal("ParseOTPURI:", err)
+	}
+	if secret != "JBSWY3DPEHPK3PXP" || o.Type != "totp" || o.Algorithm != "SHA256" || o.Digits != 8 || o.Period != 60 {
+		t.Error("Unexpected OTP", o, secret)
+	}
+	for _, bad := range []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/x?secret=not-base32!",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+	} {
+		if _, _, err := ParseOTPURI(bad); err == nil {
+			t.Error("Should fail:", bad)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package baccounts
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const DefaultPassLength = 16
+
+// MinPassLength is the shortest password Update accepts.
+const MinPassLength = 8
+
+var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+var nums = []rune("0123456789")
+
+// SetPassword encrypts pass with coder as the new password of the site.
+func (site *Site) SetPassword(coder *Coder, pass string) error {
+	encpass, err := coder.Encode(pass, 0)
+	if err != nil {
+		return err
+	}
+	site.EncodedPass = encpass
+	site.touchPass()
+	return nil
+}
+
+// GeneratePassword makes a random password of letters and digits, or of
+// digits only if numOnly.
+func GeneratePassword(length int, numOnly bool) (string, error) {
+	chars := letters
+	if numOnly {
+		chars = nums
+	}
+	bytes := make([]rune, length)
+	for i := 0; i < length; i++ {
+		j, e := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if e != nil {
+			return "", e
+		}
+		bytes[i] = chars[j.Int64()]
+	}
+	return string(bytes), nil
+}
@@ -3,16 +3,25 @@ package baccounts
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Site struct {
-	Url         string
-	Name        string
-	EncodedPass string
-	Mail        string
+	Url          string
+	Name         string
+	EncodedPass  string
+	Mail         string
+	Modified     time.Time // Last time the site was changed, see Touch
+	PassModified time.Time // Last time the password was changed, for Audit
+	OTP          *OTP      // One-time password generator, if the site has 2FA
+	Fields       map[string]*Field
+	EncodedNote  string // Free-form note, encrypted by the Coder
+	Tags         []string
+	Folder       string  // Slash-separated path, like "finance/banks"
+	SSHKey       *SSHKey // Served by ssh-agent, if set
 }
 
 type Profile struct {
@@ -26,91 +35,148 @@ func NewProfile(mail string, dflt bool) *Profile {
 	return &Profile{mail, make(map[string]*Site), dflt}
 }
 
+// SiteKey is the key in Profile.Sites of another account on a domain
+// that already has one; the first account is keyed by the domain alone.
+func SiteKey(domain, user string) string {
+	return user + "@" + domain
+}
+
+// SiteDomain is the domain of a key in Profile.Sites.
+func SiteDomain(key string) string {
+	if i := strings.LastIndex(key, "@"); i >= 0 {
+		return key[i+1:]
+	}
+	return key
+}
+
+// ChooseSite, if set, is asked to pick one when a pattern matches several
+// sites, with their keys. Without it, that is an error.
+var ChooseSite func(p *Profile, keys []string) (int, error)
+
 func (profile *Profile) AddSite(domain, url, name, encpass, mail string) error {
-	_, ok := profile.Sites[domain]
-	if ok {
-		return errors.New("Site already exists: " + domain + " (currently no pass update implemented; TODO)")
+	key := domain
+	if existing, ok := profile.Sites[domain]; ok {
+		if existing.Mail == mail || mail == "" {
+			return errors.New("Site already exists: " + domain + " (use update to change its pass, or give another mail)")
+		}
+		key = SiteKey(domain, mail)
+		if _, ok := profile.Sites[key]; ok {
+			return errors.New("Site already exists: " + key)
+		}
 	}
-	profile.Sites[domain] = &Site{url, name, encpass, mail}
+	now := time.Now()
+	profile.Sites[key] = &Site{Url: url, Name: name, EncodedPass: encpass, Mail: mail, Modified: now, PassModified: now}
 	return nil
 }
 
 func (p *Profile) FindSite(urlPattern string) (*Site, error) {
-	u, e := url.Parse(urlPattern)
-	if e != nil {
-		fmt.Println("Cannot parse URL", urlPattern, "as an URL:", e)
-		return nil, e
+	_, site, err := p.FindSiteKey(urlPattern)
+	return site, err
+}
+
+// FindSiteKey finds the site matching a URL, a domain or a part of it.
+// When a domain has several accounts, "user@domain" picks one by its
+// mail or name.
+func (p *Profile) FindSiteKey(urlPattern string) (string, *Site, error) {
+	word, user := splitPattern(urlPattern)
+	keys := p.matchSites(user, func(domain string) bool { return domain == word })
+	if len(keys) == 0 {
+		keys = p.matchSites(user, func(domain string) bool { return strings.Contains(domain, word) })
+		for _, key := range keys {
+			url := strings.Replace(p.Sites[key].Url, word, "\x1b[31m"+word+"\x1b[0m", -1)
+			fmt.Printf("Match: %s\n", url)
+		}
 	}
 
-	site, ok := p.Sites[u.Host]
-	if ok {
-		return site, nil
+	switch {
+	case len(keys) == 0:
+		return "", nil, fmt.Errorf("No site matching '%s' found", urlPattern)
+	case len(keys) == 1:
+		return keys[0], p.Sites[keys[0]], nil
+	case ChooseSite == nil:
+		return "", nil, fmt.Errorf("%d sites matched for keyword '%s': try user@domain", len(keys), urlPattern)
+	}
+	i, err := ChooseSite(p, keys)
+	if err != nil {
+		return "", nil, err
 	}
+	return keys[i], p.Sites[keys[i]], nil
+}
 
-	count := 0
-	word := urlPattern
+// FindSiteExact is FindSiteKey for a domain that has to match exactly,
+// for a password never to be handed to a host that merely looks like
+// its site.
+func (p *Profile) FindSiteExact(urlPattern string) (string, *Site, error) {
+	key, site, err := p.FindSiteKey(urlPattern)
+	if err != nil {
+		return "", nil, err
+	}
+	if word, _ := splitPattern(urlPattern); SiteDomain(key) != word {
+		return "", nil, fmt.Errorf("No site for %s", word)
+	}
+	return key, site, nil
+}
 
-	for host, site := range p.Sites {
-		if strings.Contains(host, word) {
-			count += 1
-			url := strings.Replace(site.Url, word, "\x1b[31m"+word+"\x1b[0m", -1)
-			fmt.Printf("Match: %s\n", url)
+// splitPattern splits a pattern of FindSiteKey into the domain, or part
+// of it, and the user if given.
+func splitPattern(urlPattern string) (word, user string) {
+	if u, e := url.Parse(urlPattern); e == nil && u.Host != "" {
+		if u.User != nil {
+			user = u.User.Username()
 		}
+		return u.Host, user
 	}
-	if count > 1 {
-		return nil, fmt.Errorf("%d, more than 2 site matched for keyword '%s'\n", count, word)
-	} else if count == 0 {
-		return nil, fmt.Errorf("No site matching '%s' found", word)
+	if i := strings.LastIndex(urlPattern, "@"); i >= 0 {
+		return urlPattern[i+1:], urlPattern[:i]
 	}
-	for host, site := range p.Sites {
-		if strings.Contains(host, word) {
-			fmt.Printf("One site matched for %s\n", urlPattern)
+	return urlPattern, ""
+}
 
-			return site, nil
+// matchSites returns the sorted keys of sites whose domain matches and,
+// if user is given, whose mail or name is user.
+func (p *Profile) matchSites(user string, match func(domain string) bool) []string {
+	keys := make([]string, 0)
+	for key, site := range p.Sites {
+		if !match(SiteDomain(key)) {
+			continue
 		}
+		if user != "" && site.Mail != user && site.Name != user {
+			continue
+		}
+		keys = append(keys, key)
 	}
-	log.Fatalf("Cannot reach here")
-	return nil, nil
+	sort.Strings(keys)
+	return keys
 }
 
 func (p *Profile) UpdateSite(urlPattern, encpass string) error {
-	u, e := url.Parse(urlPattern)
-	if e != nil {
-		fmt.Println("Cannot parse URL", urlPattern, "as an URL:", e)
-		return e
-	}
-
-	site, ok := p.Sites[u.Host]
-	if ok {
-		site.EncodedPass = encpass
-		return nil
+	site, err := p.FindSite(urlPattern)
+	if err != nil {
+		return err
 	}
+	site.EncodedPass = encpass
+	site.touchPass()
+	return nil
+}
 
-	count := 0
-	word := urlPattern
-
-	for host, site := range p.Sites {
-		if strings.Contains(host, word) {
-			count += 1
-			url := strings.Replace(site.Url, word, "\x1b[31m"+word+"\x1b[0m", -1)
-			fmt.Printf("Match: %s\n", url)
-		}
-	}
-	if count > 1 {
-		return fmt.Errorf("%d, more than 2 site matched for keyword '%s'\n", count, word)
-	} else if count == 0 {
-		return fmt.Errorf("No site matching '%s' found", word)
-	}
-	for host, site := range p.Sites {
-		if strings.Contains(host, word) {
-			site.EncodedPass = encpass
-			fmt.Printf("One site matched for %s\n", site.Name)
-			return nil
-		}
+// DeleteSite removes the site keyed key.
+func (p *Profile) DeleteSite(key string) error {
+	if _, ok := p.Sites[key]; !ok {
+		return errors.New("No such site: " + key)
 	}
-	log.Fatalf("Cannot reach here")
+	delete(p.Sites, key)
 	return nil
+}
+
+// Touch marks the site changed now, for merges to tell which side is newer.
+func (site *Site) Touch() {
+	site.Modified = time.Now()
+}
 
+// touchPass marks the password of the site changed now, besides the site.
+func (site *Site) touchPass() {
+	site.Touch()
+	site.PassModified = site.Modified
 }
 
 func (profile *Profile) SetDefault(b bool) {
@@ -0,0 +1,54 @@
+package baccounts
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// Render executes a text/template with secrets of the profile in it:
+//
+//	{{ secret "db.internal" }}          the password of a site
+//	{{ field "aws" "access_key" }}      a field of a site
+//	{{ username "db.internal" }}        the name, or else mail, of a site
+//
+// Sites are found like FindSite does, and secrets decrypted with coder,
+// which has the passphrase set, once each however often they're used.
+func (p *Profile) Render(coder *Coder, name, text string) ([]byte, error) {
+	secrets := make(map[string]string)
+	secret := func(ref *SecretRef) (string, error) {
+		if s, ok := secrets[ref.String()]; ok {
+			return s, nil
+		}
+		s, err := p.Secret(coder, ref)
+		if err != nil {
+			return "", err
+		}
+		secrets[ref.String()] = s
+		return s, nil
+	}
+
+	funcs := template.FuncMap{
+		"secret": func(site string) (string, error) {
+			return secret(&SecretRef{Site: site})
+		},
+		"field": func(site, field string) (string, error) {
+			return secret(&SecretRef{Site: site, Field: field})
+		},
+		"username": func(site string) (string, error) {
+			s, err := p.FindSite(site)
+			if err != nil {
+				return "", err
+			}
+			return s.Username(), nil
+		},
+	}
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
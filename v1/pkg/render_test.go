@@ -0,0 +1,34 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	coder := NewTestCoder()
+	encpass, err := coder.Encode("db-pass", 0)
+	if err != nil {
+		t.Fatal("Encode:", err)
+	}
+	p := NewProfile("me", true)
+	// Without a mail, the name is the username
+	p.AddSite("db.internal", "https://db.internal", "admin", encpass, "")
+	p.Sites["db.internal"].SetField(coder, "port", "5432", false)
+
+	text := `db.internal:{{ field "db.internal" "port" }}:*:{{ username "db.internal" }}:{{ secret "db.internal" }}
+again: {{ secret "db.internal" }}
+`
+	out, err := p.Render(coder, "pgpass", text)
+	if err != nil {
+		t.Fatal("Render:", err)
+	}
+	if string(out) != "db.internal:5432:*:admin:db-pass\nagain: db-pass\n" {
+		t.Error("Unexpected output", string(out))
+	}
+
+	for _, text := range []string{`{{ secret "nosuch.com" }}`, `{{ field "db.internal" "nosuch" }}`, `{{ secret }`} {
+		if _, err := p.Render(coder, "bad", text); err == nil {
+			t.Error("Render should fail", text)
+		}
+	}
+}
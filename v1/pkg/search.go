@@ -0,0 +1,114 @@
+package baccounts
+
+import (
+	"sort"
+	"strings"
+)
+
+// SearchResult is a site matching a search, with how well it matched.
+type SearchResult struct {
+	Profile *Profile
+	Key     string
+	Site    *Site
+	Score   int
+}
+
+// Search ranks sites of all profiles by how well every word of query
+// fuzzy-matches their domain, URL, name, mail, tags, folder or, if notes
+// is given, their decrypted note. The best matches come first.
+func (b *Baccount) Search(query string, notes func(*Site) string) []SearchResult {
+	words := strings.Fields(strings.ToLower(query))
+	results := make([]SearchResult, 0)
+	for _, p := range b.Profiles {
+		for key, site := range p.Sites {
+			targets := []struct {
+				text   string
+				weight int
+			}{
+				{SiteDomain(key), 3},
+				{site.Url, 2},
+				{site.Name, 2},
+				{site.Mail, 2},
+				{strings.Join(site.Tags, " "), 2},
+				{site.Folder, 1},
+			}
+			if notes != nil {
+				targets = append(targets, struct {
+					text   string
+					weight int
+				}{notes(site), 1})
+			}
+
+			total := 0
+			for _, word := range words {
+				best := 0
+				for _, target := range targets {
+					if score := fuzzyScore(word, target.text) * target.weight; score > best {
+						best = score
+					}
+				}
+				if best == 0 {
+					total = 0
+					break
+				}
+				total += best
+			}
+			if total > 0 || len(words) == 0 {
+				results = append(results, SearchResult{p, key, site, total})
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Profile.Name != results[j].Profile.Name {
+			return results[i].Profile.Name < results[j].Profile.Name
+		}
+		return results[i].Key < results[j].Key
+	})
+	return results
+}
+
+// fuzzyScore tells how well word matches text: best when equal, then as
+// a prefix, a substring, and last as a subsequence with few gaps. Zero
+// means no match.
+func fuzzyScore(word, text string) int {
+	text = strings.ToLower(text)
+	switch {
+	case word == "" || text == "":
+		return 0
+	case text == word:
+		return 100
+	case strings.HasPrefix(text, word):
+		return 90
+	}
+	if i := strings.Index(text, word); i >= 0 {
+		if strings.ContainsRune(" ./@:-_", rune(text[i-1])) {
+			return 80
+		}
+		return 70
+	}
+
+	// Subsequence: every rune of word in order, penalized by the gaps
+	w := []rune(word)
+	first, last, k := -1, 0, 0
+	for i, c := range []rune(text) {
+		if k < len(w) && c == w[k] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+			k++
+		}
+	}
+	if k < len(w) {
+		return 0
+	}
+	score := 50 - (last - first + 1 - len(w))
+	if score < 1 {
+		score = 1
+	}
+	return score
+}
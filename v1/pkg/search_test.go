@@ -0,0 +1,64 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	me := NewProfile("me", true)
+	me.AddSite("github.com", "https://github.com", "me", "x", "me@mac.com")
+	me.AddSite("gitlab.com", "https://gitlab.com", "me", "x", "me@mac.com")
+	me.AddSite("bank.example.com", "https://bank.example.com", "me", "x", "me@mac.com")
+	work := NewProfile("work", false)
+	work.AddSite("github.com", "https://github.com", "work", "x", "me@work.com")
+	work.Sites["github.com"].AddTags("work")
+	b := &Baccount{Profiles: []*Profile{me, work}}
+
+	results := b.Search("github", nil)
+	if len(results) != 2 || results[0].Key != "github.com" || results[1].Key != "github.com" {
+		t.Fatal("Unexpected results", results)
+	}
+
+	results = b.Search("github work", nil)
+	if len(results) != 1 || results[0].Profile != work {
+		t.Error("All words should match", results)
+	}
+
+	results = b.Search("gthb", nil)
+	if len(results) != 2 || results[0].Key != "github.com" {
+		t.Error("Fuzzy match failed", results)
+	}
+
+	results = b.Search("secret", func(site *Site) string {
+		if site.Url == "https://bank.example.com" {
+			return "secret question"
+		}
+		return ""
+	})
+	if len(results) != 1 || results[0].Key != "bank.example.com" {
+		t.Error("Note not searched", results)
+	}
+
+	if results := b.Search("nosuchsite", nil); len(results) != 0 {
+		t.Error("Unexpected results", results)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	cases := []struct {
+		word, text string
+		expected   int
+	}{
+		{"github.com", "github.com", 100},
+		{"git", "github.com", 90},
+		{"hub", "github.com", 70},
+		{"com", "github.com", 80},
+		{"ghc", "github.com", 45},
+		{"xyz", "github.com", 0},
+	}
+	for _, c := range cases {
+		if score := fuzzyScore(c.word, c.text); score != c.expected {
+			t.Error("Unexpected score", c, score)
+		}
+	}
+}
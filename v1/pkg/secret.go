@@ -0,0 +1,48 @@
+package baccounts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretRef names a secret of a site: its password, or one of its
+// fields. It's written "site:pattern" or "field:pattern#name", with a
+// pattern FindSite takes.
+type SecretRef struct {
+	Site  string
+	Field string // Empty for the password
+}
+
+func ParseSecretRef(ref string) (*SecretRef, error) {
+	kind, rest, ok := strings.Cut(ref, ":")
+	switch {
+	case ok && kind == "site" && rest != "":
+		return &SecretRef{Site: rest}, nil
+	case ok && kind == "field":
+		i := strings.LastIndex(rest, "#")
+		if i > 0 && i < len(rest)-1 {
+			return &SecretRef{Site: rest[:i], Field: rest[i+1:]}, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid secret %q: give site:host or field:host#name", ref)
+}
+
+func (r *SecretRef) String() string {
+	if r.Field == "" {
+		return "site:" + r.Site
+	}
+	return "field:" + r.Site + "#" + r.Field
+}
+
+// Secret finds the site of the reference and decrypts the secret with
+// coder, which has the passphrase set.
+func (p *Profile) Secret(coder *Coder, ref *SecretRef) (string, error) {
+	site, err := p.FindSite(ref.Site)
+	if err != nil {
+		return "", err
+	}
+	if ref.Field == "" {
+		return coder.Decode(site.EncodedPass)
+	}
+	return site.GetField(coder, ref.Field)
+}
@@ -0,0 +1,59 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	for ref, expected := range map[string]SecretRef{
+		"site:db.internal":            {Site: "db.internal"},
+		"site:me@github.com":          {Site: "me@github.com"},
+		"field:aws.amazon.com#key_id": {Site: "aws.amazon.com", Field: "key_id"},
+		"field:https://a.com/#x#pin":  {Site: "https://a.com/#x", Field: "pin"},
+		"site:git.example.com:8443":   {Site: "git.example.com:8443"},
+	} {
+		r, err := ParseSecretRef(ref)
+		if err != nil || *r != expected {
+			t.Error("Unexpected reference for", ref, r, err)
+			continue
+		}
+		if r.String() != ref {
+			t.Error("Unexpected string", r.String())
+		}
+	}
+	for _, ref := range []string{"", "db.internal", "site:", "field:a.com", "field:a.com#", "pass:a.com"} {
+		if _, err := ParseSecretRef(ref); err == nil {
+			t.Error("Invalid reference accepted", ref)
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	coder := NewTestCoder()
+	encpass, err := coder.Encode("db-pass", 0)
+	if err != nil {
+		t.Fatal("Encode:", err)
+	}
+	p := NewProfile("me", true)
+	p.AddSite("db.internal", "https://db.internal", "me", encpass, "me@mac.com")
+	site := p.Sites["db.internal"]
+	site.SetField(coder, "pin", "1234", true)
+	site.SetField(coder, "user", "admin", false)
+
+	for ref, expected := range map[string]string{
+		"site:db.internal":       "db-pass",
+		"field:db.internal#pin":  "1234",
+		"field:db.internal#user": "admin",
+	} {
+		r, _ := ParseSecretRef(ref)
+		if secret, err := p.Secret(coder, r); err != nil || secret != expected {
+			t.Error("Unexpected secret of", ref, secret, err)
+		}
+	}
+	for _, ref := range []string{"site:nosuch.com", "field:db.internal#nosuch"} {
+		r, _ := ParseSecretRef(ref)
+		if _, err := p.Secret(coder, r); err == nil {
+			t.Error("Missing secret found", ref)
+		}
+	}
+}
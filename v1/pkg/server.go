@@ -0,0 +1,380 @@
+package baccounts
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServeSocket is where serve listens: $BACCOUNTS_SOCK, or else in the
+// runtime directory of the user.
+func ServeSocket() string {
+	if sock := os.Getenv("BACCOUNTS_SOCK"); sock != "" {
+		return sock
+	}
+	return filepath.Join(runtimeDir(), "baccounts.sock")
+}
+
+// Server is the JSON API of serve, for tools not to parse what commands
+// print. Besides the permissions of its socket, clients may have to send
+// a token as "Authorization: Bearer <token>". It answers
+//
+//	GET  /v1/sites?profile=&tag=&folder=   SiteInfo of sites
+//	GET  /v1/search?q=                     SiteInfo of matching sites, best first
+//	POST /v1/secret    SecretRequest       {"Secret": ...}
+//	POST /v1/generate  GenerateRequest     {"Key": ..., "Secret": ...}
+//	POST /v1/update    UpdateRequest       {"Key": ..., "Warning": ...}
+//
+// and {"Error": ...} on failure. Every request is logged with the site it
+// read or changed, without secrets.
+type Server struct {
+	b        *Baccount
+	datafile string
+	coder    *Coder            // With the passphrase set
+	tokens   map[string]string // Client names by token
+	mux      *http.ServeMux
+	mu       sync.Mutex // Of b
+}
+
+// SiteInfo is what the API tells about a site, secrets left out.
+type SiteInfo struct {
+	Profile  string
+	Key      string
+	Url      string
+	Name     string
+	Mail     string
+	Tags     []string
+	Folder   string
+	Fields   []string // Names only
+	OTP      bool
+	Modified time.Time
+	Score    int // Of search only
+}
+
+type SecretRequest struct {
+	Profile string // The default one if empty
+	Site    string // Pattern of FindSiteExact
+	Field   string // The password if empty
+}
+
+type GenerateRequest struct {
+	Profile string
+	Url     string
+	Mail    string
+	Length  int // DefaultPassLength if 0
+	NumOnly bool
+}
+
+type UpdateRequest struct {
+	Profile  string
+	Site     string
+	Password string
+}
+
+// NewServer serves b, saved to datafile, decrypting with coder. tokens
+// are those of Config.Tokens.
+func NewServer(b *Baccount, datafile string, coder *Coder, tokens map[string]string) *Server {
+	s := &Server{b: b, datafile: datafile, coder: coder, tokens: make(map[string]string), mux: http.NewServeMux()}
+	for client, token := range tokens {
+		if token != "" {
+			s.tokens[token] = client
+		}
+	}
+	s.handle("/v1/sites", http.MethodGet, s.sites)
+	s.handle("/v1/search", http.MethodGet, s.search)
+	s.handle("/v1/secret", http.MethodPost, s.secret)
+	s.handle("/v1/generate", http.MethodPost, s.generate)
+	s.handle("/v1/update", http.MethodPost, s.update)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	client, ok := s.client(r)
+	if ok {
+		s.mux.ServeHTTP(rec, r)
+	} else {
+		writeJSON(rec, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
+	}
+	args := []interface{}{"client", client, "method", r.Method, "path", r.URL.Path}
+	if a := rec.accessed; a.Key != "" {
+		args = append(args, "profile", a.Profile, "site", a.Key)
+		if a.Field != "" {
+			args = append(args, "field", a.Field)
+		}
+	}
+	args = append(args, "status", rec.status, "duration", time.Since(start))
+	slog.Info("request", args...)
+}
+
+// accessed is the site a request read or changed, for the log.
+type accessed struct {
+	Profile string
+	Key     string
+	Field   string // Of a secret that is a field
+}
+
+// client tells who sent the request by its token, if tokens are needed.
+func (s *Server) client(r *http.Request) (string, bool) {
+	if len(s.tokens) == 0 {
+		return "-", true
+	}
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || token == "" {
+		return "unknown", false
+	}
+	for t, client := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return client, true
+		}
+	}
+	return "unknown", false
+}
+
+// httpError is an error with the status to answer it with.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(err error) error {
+	return &httpError{http.StatusBadRequest, err}
+}
+
+func notFound(err error) error {
+	return &httpError{http.StatusNotFound, err}
+}
+
+// handle serves path by h, one request at a time, with a datafile as
+// fresh as it is on disk.
+func (s *Server) handle(path, method string, h func(r *http.Request, a *accessed) (interface{}, error)) {
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		a := &accessed{}
+		if rec, ok := w.(*statusRecorder); ok {
+			a = &rec.accessed
+		}
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"Error": "Use " + method})
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var resp interface{}
+		err := s.b.Reload()
+		if err == nil {
+			resp, err = h(r, a)
+		}
+		if err != nil {
+			// Don't let the next request save what failed
+			s.b.discard()
+		}
+		var herr *httpError
+		switch {
+		case errors.As(err, &herr):
+			writeJSON(w, herr.status, map[string]string{"Error": herr.Error()})
+		case err != nil:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"Error": err.Error()})
+		default:
+			writeJSON(w, http.StatusOK, resp)
+		}
+	})
+}
+
+func (s *Server) sites(r *http.Request, _ *accessed) (interface{}, error) {
+	q := r.URL.Query()
+	opts := ListOptions{Tag: q.Get("tag"), Folder: q.Get("folder")}
+	infos := make([]*SiteInfo, 0)
+	for _, p := range s.b.Profiles {
+		if name := q.Get("profile"); name != "" && p.Name != name {
+			continue
+		}
+		for key, site := range p.Sites {
+			if opts.match(site) {
+				infos = append(infos, siteInfo(p, key, site))
+			}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Profile != infos[j].Profile {
+			return infos[i].Profile < infos[j].Profile
+		}
+		return infos[i].Key < infos[j].Key
+	})
+	return infos, nil
+}
+
+func (s *Server) search(r *http.Request, _ *accessed) (interface{}, error) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		return nil, badRequest(fmt.Errorf("No query given as q"))
+	}
+	results := s.b.Search(query, nil)
+	infos := make([]*SiteInfo, len(results))
+	for i, result := range results {
+		infos[i] = siteInfo(result.Profile, result.Key, result.Site)
+		infos[i].Score = result.Score
+	}
+	return infos, nil
+}
+
+func (s *Server) secret(r *http.Request, a *accessed) (interface{}, error) {
+	var req SecretRequest
+	p, err := s.request(r, &req, &req.Profile)
+	if err != nil {
+		return nil, err
+	}
+	key, site, err := p.FindSiteExact(req.Site)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	*a = accessed{p.Name, key, req.Field}
+
+	var secret string
+	if req.Field == "" {
+		secret, err = s.coder.Decode(site.EncodedPass)
+	} else if _, ok := site.Fields[req.Field]; !ok {
+		return nil, notFound(fmt.Errorf("No field %s in %s", req.Field, site.Url))
+	} else {
+		secret, err = site.GetField(s.coder, req.Field)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"Secret": secret}, nil
+}
+
+func (s *Server) generate(r *http.Request, a *accessed) (interface{}, error) {
+	var req GenerateRequest
+	p, err := s.request(r, &req, &req.Profile)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(req.Url)
+	if err != nil || u.Host == "" {
+		return nil, badRequest(fmt.Errorf("Invalid URL: %q", req.Url))
+	}
+	if req.Length == 0 {
+		req.Length = DefaultPassLength
+	}
+	if req.Length < MinPassLength {
+		return nil, badRequest(fmt.Errorf("Length should be at least %d", MinPassLength))
+	}
+
+	pass, err := GeneratePassword(req.Length, req.NumOnly)
+	if err != nil {
+		return nil, err
+	}
+	encpass, err := s.coder.Encode(pass, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.AddSite(u.Host, req.Url, p.Name, encpass, req.Mail); err != nil {
+		return nil, badRequest(err)
+	}
+	key, _ := p.findAccount(u.Host, req.Mail)
+	*a = accessed{Profile: p.Name, Key: key}
+	s.b.SetMessage("Generate password for %s @ %s", u.Host, p.Name)
+	if err := s.b.UpdateConfigFile(s.datafile); err != nil {
+		return nil, err
+	}
+	return map[string]string{"Key": key, "Secret": pass}, nil
+}
+
+func (s *Server) update(r *http.Request, a *accessed) (interface{}, error) {
+	var req UpdateRequest
+	p, err := s.request(r, &req, &req.Profile)
+	if err != nil {
+		return nil, err
+	}
+	key, site, err := p.FindSiteExact(req.Site)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	*a = accessed{Profile: p.Name, Key: key}
+	strength, err := CheckNewPassword(req.Password)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	if err := site.SetPassword(s.coder, req.Password); err != nil {
+		return nil, err
+	}
+	s.b.SetMessage("Update password for %s @ %s", key, p.Name)
+	if err := s.b.UpdateConfigFile(s.datafile); err != nil {
+		return nil, err
+	}
+	resp := map[string]string{"Key": key}
+	if strength.Score < MinScore {
+		resp["Warning"] = fmt.Sprintf("Password is %s: %s", strength, strings.Join(strength.Reasons, ", "))
+	}
+	return resp, nil
+}
+
+// request decodes the JSON body of r into req and finds the profile it
+// names.
+func (s *Server) request(r *http.Request, req interface{}, profile *string) (*Profile, error) {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return nil, badRequest(fmt.Errorf("Invalid request: %v", err))
+	}
+	if *profile == "" {
+		p, err := s.b.GetDefault()
+		if err != nil {
+			return nil, notFound(err)
+		}
+		return p, nil
+	}
+	p := s.b.findProfile(*profile)
+	if p == nil {
+		return nil, notFound(fmt.Errorf("Profile not found: %s", *profile))
+	}
+	return p, nil
+}
+
+func siteInfo(p *Profile, key string, site *Site) *SiteInfo {
+	return &SiteInfo{
+		Profile:  p.Name,
+		Key:      key,
+		Url:      site.Url,
+		Name:     site.Name,
+		Mail:     site.Mail,
+		Tags:     site.Tags,
+		Folder:   site.Folder,
+		Fields:   site.FieldNames(),
+		OTP:      site.OTP != nil,
+		Modified: site.Modified,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status   int
+	accessed accessed
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
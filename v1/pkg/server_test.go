@@ -0,0 +1,169 @@
+package baccounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T, tokens map[string]string) (*Server, string) {
+	coder := NewTestCoder()
+	encpass, err := coder.Encode("db-pass-1234", 0)
+	if err != nil {
+		t.Fatal("Encode:", err)
+	}
+	p := NewProfile("me", true)
+	p.AddSite("db.internal", "https://db.internal", "me", encpass, "me@mac.com")
+	p.Sites["db.internal"].AddTags("db")
+	p.Sites["db.internal"].SetField(coder, "pin", "1234", true)
+	p.AddSite("github.com", "https://github.com", "me", encpass, "me@mac.com")
+
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	b := &Baccount{Profiles: []*Profile{p}, Version: Version}
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	b, err = LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	return NewServer(b, datafile, coder, tokens), datafile
+}
+
+func call(t *testing.T, s *Server, method, path, body, token string, resp interface{}) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if resp != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), resp); err != nil {
+			t.Error("Unexpected response", path, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestServer(t *testing.T) {
+	s, datafile := newTestServer(t, nil)
+
+	var sites []SiteInfo
+	if code := call(t, s, "GET", "/v1/sites?tag=db", "", "", &sites); code != 200 || len(sites) != 1 || sites[0].Key != "db.internal" || sites[0].Fields[0] != "pin" {
+		t.Error("Unexpected sites", code, sites)
+	}
+	if code := call(t, s, "GET", "/v1/search?q=gthb", "", "", &sites); code != 200 || len(sites) != 1 || sites[0].Key != "github.com" || sites[0].Score == 0 {
+		t.Error("Unexpected search", code, sites)
+	}
+
+	var resp map[string]string
+	if code := call(t, s, "POST", "/v1/secret", `{"Site": "db.internal"}`, "", &resp); code != 200 || resp["Secret"] != "db-pass-1234" {
+		t.Error("Unexpected secret", code, resp)
+	}
+	if code := call(t, s, "POST", "/v1/secret", `{"Site": "db.internal", "Field": "pin"}`, "", &resp); code != 200 || resp["Secret"] != "1234" {
+		t.Error("Unexpected field", code, resp)
+	}
+	if code := call(t, s, "POST", "/v1/secret", `{"Site": "db"}`, "", &resp); code != 404 {
+		t.Error("Partial domain should not be found", code, resp)
+	}
+	if code := call(t, s, "GET", "/v1/secret", "", "", nil); code != 405 {
+		t.Error("GET of secret should not be allowed", code)
+	}
+
+	resp = nil
+	if code := call(t, s, "POST", "/v1/generate", `{"Url": "https://new.example.com/login", "Length": 20}`, "", &resp); code != 200 || resp["Key"] != "new.example.com" || len(resp["Secret"]) != 20 {
+		t.Error("Unexpected generate", code, resp)
+	}
+	if code := call(t, s, "POST", "/v1/update", `{"Site": "github.com", "Password": "short"}`, "", &resp); code != 400 {
+		t.Error("Short password should be rejected", code, resp)
+	}
+	if code := call(t, s, "POST", "/v1/update", `{"Site": "github.com", "Password": "x7#Kp9!vQ2mZ"}`, "", &resp); code != 200 || resp["Key"] != "github.com" {
+		t.Error("Unexpected update", code, resp)
+	}
+
+	b, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	if _, ok := b.Profiles[0].Sites["new.example.com"]; !ok {
+		t.Error("Generated site not saved")
+	}
+	if pass, _ := s.coder.Decode(b.Profiles[0].Sites["github.com"].EncodedPass); pass != "x7#Kp9!vQ2mZ" {
+		t.Error("Updated password not saved", pass)
+	}
+
+	// Changes of other writers are seen
+	b.Profiles[0].AddSite("other.com", "https://other.com", "me", "x", "me@mac.com")
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	if code := call(t, s, "GET", "/v1/sites", "", "", &sites); code != 200 || len(sites) != 4 {
+		t.Error("Change on disk not seen", code, sites)
+	}
+}
+
+func TestServerFailedSave(t *testing.T) {
+	s, datafile := newTestServer(t, nil)
+
+	s.b.ReadOnly = true
+	if code := call(t, s, "POST", "/v1/update", `{"Site": "github.com", "Password": "x7#Kp9!vQ2mZ"}`, "", nil); code != 500 {
+		t.Error("Update should fail to save", code)
+	}
+	s.b.ReadOnly = false
+	if code := call(t, s, "POST", "/v1/generate", `{"Url": "https://new.example.com"}`, "", nil); code != 200 {
+		t.Error("Unexpected generate", code)
+	}
+
+	// The next save doesn't save what failed
+	b, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+	if pass, _ := s.coder.Decode(b.Profiles[0].Sites["github.com"].EncodedPass); pass != "db-pass-1234" {
+		t.Error("Failed update saved later", pass)
+	}
+}
+
+func TestServerTokens(t *testing.T) {
+	s, _ := newTestServer(t, map[string]string{"deploy": "s3cret"})
+
+	for token, expected := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "s3cret": http.StatusOK} {
+		if code := call(t, s, "GET", "/v1/sites", "", token, nil); code != expected {
+			t.Error("Unexpected status with token", token, code)
+		}
+	}
+	req := httptest.NewRequest("GET", "/v1/sites", nil)
+	req.Header.Set("Authorization", "s3cret")
+	rec := httptest.NewRecorder()
+	if s.ServeHTTP(rec, req); rec.Code != http.StatusUnauthorized {
+		t.Error("Token without Bearer accepted", rec.Code)
+	}
+}
+
+func TestServerLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	s, _ := newTestServer(t, nil)
+	call(t, s, "POST", "/v1/secret", `{"Site": "db.internal", "Field": "pin"}`, "", nil)
+	call(t, s, "POST", "/v1/update", `{"Site": "github.com", "Password": "Hq8Zn4Wc1Rv6Ty3B"}`, "", nil)
+
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "msg=request") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 || !strings.Contains(lines[0], "profile=me site=db.internal field=pin") || !strings.Contains(lines[1], "profile=me site=github.com") {
+		t.Error("Unexpected log", buf.String())
+	}
+	if strings.Contains(buf.String(), "1234") || strings.Contains(buf.String(), "Hq8Zn4Wc1Rv6Ty3B") {
+		t.Error("Secrets logged", buf.String())
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package baccounts
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkSocketDir makes sure no other user can get at sockets in dir, as
+// one who could would swap them for their own, like ssh-agent does.
+func checkSocketDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !fi.IsDir() || !ok || int(st.Uid) != os.Getuid() || fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s has to be a directory of yours with mode 0700", dir)
+	}
+	return nil
+}
+
+// listenUnix creates the socket with no access for others from the start.
+func listenUnix(sock string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", sock)
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package baccounts
+
+import (
+	"net"
+)
+
+// checkSocketDir leaves access to the ACLs of dir, which the user's
+// profile directories already restrict.
+func checkSocketDir(dir string) error {
+	return nil
+}
+
+func listenUnix(sock string) (net.Listener, error) {
+	return net.Listen("unix", sock)
+}
@@ -0,0 +1,176 @@
+package baccounts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var errSSHAgentVault = errors.New("Keys of this agent are those of the vault: use ssh-key to change them")
+
+// SSHAgentSocket is where ssh-agent listens by default, in the runtime
+// directory of the user.
+func SSHAgentSocket() string {
+	return filepath.Join(runtimeDir(), "baccounts-ssh-agent.sock")
+}
+
+// SSHAgent is an ssh-agent of the SSH keys of all sites. It lists them
+// from their public keys, and decrypts one only to sign with it, once
+// Confirm agrees, keeping it for Lifetime at most.
+type SSHAgent struct {
+	b        *Baccount
+	NewCoder func() (*Coder, error)     // With the passphrase set, for each key to decrypt
+	Confirm  func(question string) bool // Every use is confirmed if set
+	Lifetime time.Duration              // Decrypted keys are dropped after each use if 0
+
+	keyring agent.ExtendedAgent // Of decrypted keys
+	mu      sync.Mutex
+}
+
+func NewSSHAgent(b *Baccount) *SSHAgent {
+	return &SSHAgent{b: b, keyring: agent.NewKeyring().(agent.ExtendedAgent)}
+}
+
+type sshKeySite struct {
+	pub     ssh.PublicKey
+	comment string
+	site    *Site
+}
+
+// keys are the SSH keys of the vault as it is on disk now.
+func (a *SSHAgent) keys() ([]sshKeySite, error) {
+	if err := a.b.Reload(); err != nil {
+		return nil, err
+	}
+	keys := make([]sshKeySite, 0)
+	for _, p := range a.b.Profiles {
+		for key, site := range p.Sites {
+			if site.SSHKey == nil {
+				continue
+			}
+			pub, err := site.SSHKey.Public()
+			if err != nil {
+				return nil, fmt.Errorf("Invalid SSH key of %s @ %s: %v", key, p.Name, err)
+			}
+			keys = append(keys, sshKeySite{pub, key + " @ " + p.Name, site})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].comment < keys[j].comment })
+	return keys, nil
+}
+
+func (a *SSHAgent) find(key ssh.PublicKey) (*sshKeySite, error) {
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if bytes.Equal(keys[i].pub.Marshal(), key.Marshal()) {
+			return &keys[i], nil
+		}
+	}
+	return nil, errors.New("No such key")
+}
+
+func (a *SSHAgent) List() ([]*agent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*agent.Key, len(keys))
+	for i, k := range keys {
+		list[i] = &agent.Key{Format: k.pub.Type(), Blob: k.pub.Marshal(), Comment: k.comment}
+	}
+	return list, nil
+}
+
+func (a *SSHAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+func (a *SSHAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k, err := a.find(key)
+	if err != nil {
+		return nil, err
+	}
+	if a.Confirm != nil && !a.Confirm(fmt.Sprintf("Sign with the SSH key of %s (%s)?", k.comment, ssh.FingerprintSHA256(k.pub))) {
+		return nil, errors.New("Denied")
+	}
+
+	if !a.decrypted(key) {
+		coder, err := a.NewCoder()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := k.site.SSHPrivateKey(coder)
+		if err != nil {
+			return nil, err
+		}
+		added := agent.AddedKey{PrivateKey: raw, Comment: k.comment, LifetimeSecs: uint32(a.Lifetime / time.Second)}
+		if err := a.keyring.Add(added); err != nil {
+			return nil, err
+		}
+	}
+	sig, err := a.keyring.SignWithFlags(key, data, flags)
+	if a.Lifetime < time.Second {
+		a.keyring.Remove(key)
+	}
+	return sig, err
+}
+
+func (a *SSHAgent) decrypted(key ssh.PublicKey) bool {
+	keys, _ := a.keyring.List()
+	for _, k := range keys {
+		if bytes.Equal(k.Blob, key.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove drops the decrypted copy of the key, if any.
+func (a *SSHAgent) Remove(key ssh.PublicKey) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keyring.Remove(key)
+	return nil
+}
+
+// RemoveAll drops all decrypted keys, like ssh-add -D.
+func (a *SSHAgent) RemoveAll() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.keyring.RemoveAll()
+}
+
+func (a *SSHAgent) Add(key agent.AddedKey) error {
+	return errSSHAgentVault
+}
+
+func (a *SSHAgent) Lock(passphrase []byte) error {
+	return errSSHAgentVault
+}
+
+func (a *SSHAgent) Unlock(passphrase []byte) error {
+	return errSSHAgentVault
+}
+
+// Signers would hand out all keys without confirmation
+func (a *SSHAgent) Signers() ([]ssh.Signer, error) {
+	return nil, errSSHAgentVault
+}
+
+func (a *SSHAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
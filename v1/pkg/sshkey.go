@@ -0,0 +1,68 @@
+package baccounts
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHKey is an SSH private key of a site, encrypted by the Coder, with
+// its public key in the clear for ssh-agent to list it without asking.
+type SSHKey struct {
+	EncodedKey string // PEM in the OpenSSH format
+	PublicKey  string // Like a line of authorized_keys
+}
+
+// SetSSHKey keeps a private key in PEM encrypted with coder. A key with a
+// passphrase of its own is decrypted with passphrase first, nil if none
+// was asked for yet.
+func (site *Site) SetSSHKey(coder *Coder, pemBytes, passphrase []byte, comment string) error {
+	raw, err := ssh.ParseRawPrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) && passphrase != nil {
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+	if k, ok := raw.(*ed25519.PrivateKey); ok {
+		raw = *k
+	}
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return err
+	}
+	block, err := ssh.MarshalPrivateKey(raw, comment)
+	if err != nil {
+		return err
+	}
+	enc, err := coder.Encode(string(pem.EncodeToMemory(block)), 0)
+	if err != nil {
+		return err
+	}
+
+	site.SSHKey = &SSHKey{enc, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))}
+	site.Touch()
+	return nil
+}
+
+// SSHPrivateKey decrypts the SSH key of the site with coder.
+func (site *Site) SSHPrivateKey(coder *Coder) (interface{}, error) {
+	if site.SSHKey == nil {
+		return nil, fmt.Errorf("No SSH key for %s", site.Url)
+	}
+	pemString, err := coder.Decode(site.SSHKey.EncodedKey)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParseRawPrivateKey([]byte(pemString))
+}
+
+func (k *SSHKey) Public() (ssh.PublicKey, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.PublicKey))
+	return pub, err
+}
@@ -0,0 +1,141 @@
+package baccounts
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func newTestSSHKey(t *testing.T, passphrase []byte) (ssh.PublicKey, []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey:", err)
+	}
+	var block *pem.Block
+	if passphrase == nil {
+		block, err = ssh.MarshalPrivateKey(priv, "me@laptop")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "me@laptop", passphrase)
+	}
+	if err != nil {
+		t.Fatal("MarshalPrivateKey:", err)
+	}
+	sshPub, _ := ssh.NewPublicKey(pub)
+	return sshPub, pem.EncodeToMemory(block)
+}
+
+func TestSSHKey(t *testing.T) {
+	coder := NewTestCoder()
+	pub, pemBytes := newTestSSHKey(t, []byte("ssh-pass"))
+	site := &Site{Url: "https://github.com"}
+
+	if err := site.SetSSHKey(coder, pemBytes, nil, "github"); err == nil {
+		t.Error("Key with a passphrase should need it")
+	}
+	if err := site.SetSSHKey(coder, pemBytes, []byte("wrong"), "github"); err == nil {
+		t.Error("Wrong passphrase should fail")
+	}
+	if err := site.SetSSHKey(coder, pemBytes, []byte("ssh-pass"), "github"); err != nil {
+		t.Fatal("SetSSHKey:", err)
+	}
+	if got, err := site.SSHKey.Public(); err != nil || string(got.Marshal()) != string(pub.Marshal()) {
+		t.Error("Unexpected public key", site.SSHKey.PublicKey, err)
+	}
+
+	raw, err := site.SSHPrivateKey(coder)
+	if err != nil {
+		t.Fatal("SSHPrivateKey:", err)
+	}
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil || string(signer.PublicKey().Marshal()) != string(pub.Marshal()) {
+		t.Error("Unexpected private key", err)
+	}
+}
+
+func TestSSHAgent(t *testing.T) {
+	coder := NewTestCoder()
+	pub, pemBytes := newTestSSHKey(t, nil)
+	p := NewProfile("me", true)
+	p.AddSite("github.com", "https://github.com", "me", "", "me@mac.com")
+	if err := p.Sites["github.com"].SetSSHKey(coder, pemBytes, nil, "github"); err != nil {
+		t.Fatal("SetSSHKey:", err)
+	}
+	p.AddSite("example.com", "https://example.com", "me", "", "me@mac.com")
+
+	datafile := filepath.Join(t.TempDir(), "baccounts.json")
+	b := &Baccount{Profiles: []*Profile{p}, Version: Version}
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		t.Fatal("UpdateConfigFile:", err)
+	}
+	b, err := LoadKeys(datafile)
+	if err != nil {
+		t.Fatal("LoadKeys:", err)
+	}
+
+	a := NewSSHAgent(b)
+	decrypted := 0
+	a.NewCoder = func() (*Coder, error) {
+		decrypted++
+		return coder, nil
+	}
+	confirm := true
+	a.Confirm = func(string) bool { return confirm }
+
+	c1, c2 := net.Pipe()
+	go agent.ServeAgent(a, c2)
+	client := agent.NewClient(c1)
+	defer c1.Close()
+
+	keys, err := client.List()
+	if err != nil || len(keys) != 1 || keys[0].Comment != "github.com @ me" {
+		t.Fatal("Unexpected keys", keys, err)
+	}
+	if decrypted != 0 {
+		t.Error("List should not decrypt keys")
+	}
+
+	sig, err := client.Sign(pub, []byte("data"))
+	if err != nil {
+		t.Fatal("Sign:", err)
+	}
+	if err := pub.Verify([]byte("data"), sig); err != nil {
+		t.Error("Verify:", err)
+	}
+	if kept, _ := a.keyring.List(); len(kept) != 0 {
+		t.Error("Key should not be kept without lifetime", kept)
+	}
+
+	confirm = false
+	if _, err := client.Sign(pub, []byte("data")); err == nil {
+		t.Error("Denied sign should fail")
+	}
+	if decrypted != 1 {
+		t.Error("Denied sign should not decrypt", decrypted)
+	}
+	if err := client.Add(agent.AddedKey{}); err == nil {
+		t.Error("Add should fail")
+	}
+
+	confirm = true
+	a.Lifetime = time.Minute
+	for i := 0; i < 2; i++ {
+		if _, err := client.Sign(pub, []byte("data")); err != nil {
+			t.Fatal("Sign:", err)
+		}
+	}
+	if decrypted != 2 {
+		t.Error("Key should be kept for its lifetime", decrypted)
+	}
+
+	other, _ := newTestSSHKey(t, nil)
+	if _, err := client.Sign(other, []byte("data")); err == nil {
+		t.Error("Unknown key should fail")
+	}
+}
@@ -0,0 +1,379 @@
+package baccounts
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Strength is an estimate of how hard a password is to guess, after the
+// ideas of zxcvbn: the password is split into the cheapest sequence of
+// patterns an attacker would try (dictionary words, keyboard runs,
+// repeats, sequences and dates), with anything else brute-forced.
+type Strength struct {
+	Score   int // 0 (too guessable) to 4 (very unguessable)
+	Guesses float64
+	Reasons []string
+}
+
+// Scores below this are rejected by Update and reported by audit
+const MinScore = 3
+
+var scoreNames = []string{"too guessable", "very guessable", "somewhat guessable", "safely unguessable", "very unguessable"}
+
+func (s *Strength) String() string {
+	return fmt.Sprintf("%d/4 (%s)", s.Score, scoreNames[s.Score])
+}
+
+// Only this many runes of a password are estimated: any longer one is
+// scored by its beginning alone, not taken as stronger for its tail.
+const maxStrengthRunes = 64
+
+type strengthMatch struct {
+	i, j    int // Rune positions, both inclusive
+	guesses float64
+	reason  string
+}
+
+// EstimateStrength scores a password.
+func EstimateStrength(pass string) *Strength {
+	runes := []rune(pass)
+	if len(runes) > maxStrengthRunes {
+		runes = runes[:maxStrengthRunes]
+	}
+	s := estimateGuesses(runes, make(map[string]float64))
+
+	log := math.Log10(s.Guesses)
+	switch {
+	case log < 3:
+		s.Score = 0
+	case log < 6:
+		s.Score = 1
+	case log < 8:
+		s.Score = 2
+	case log < 10:
+		s.Score = 3
+	default:
+		s.Score = 4
+	}
+	if len([]rune(pass)) < MinPassLength {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("shorter than %d chars", MinPassLength))
+		if s.Score > 1 {
+			s.Score = 1
+		}
+	}
+	return s
+}
+
+// estimateGuesses finds the cheapest sequence of patterns for runes.
+// units caches the guesses of units of repeats, which are estimated the
+// same way.
+func estimateGuesses(runes []rune, units map[string]float64) *Strength {
+	n := len(runes)
+	matches := strengthMatches(runes, units)
+
+	// best[k] is the fewest guesses for the first k runes
+	best := make([]float64, n+1)
+	last := make([]*strengthMatch, n+1)
+	best[0] = 1
+	for k := 1; k <= n; k++ {
+		best[k] = best[k-1] * 10
+		for m := range matches {
+			match := &matches[m]
+			if match.j != k-1 {
+				continue
+			}
+			if g := best[match.i] * match.guesses; g < best[k] {
+				best[k] = g
+				last[k] = match
+			}
+		}
+	}
+
+	s := &Strength{Guesses: best[n]}
+	for k := n; k > 0; {
+		if m := last[k]; m != nil {
+			s.Reasons = append([]string{m.reason}, s.Reasons...)
+			k = m.i
+		} else {
+			k--
+		}
+	}
+	return s
+}
+
+func strengthMatches(runes []rune, units map[string]float64) []strengthMatch {
+	matches := dictionaryMatches(runes)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, units)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	return matches
+}
+
+var commonWords = func() map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(commonWordList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+// Common passwords first, then common words and names, most common first
+const commonWordList = `
+password 123456 12345678 qwerty abc123 monkey letmein dragon 111111 baseball
+iloveyou trustno1 1234567 sunshine master 123123 welcome shadow ashley football
+jesus michael ninja mustang password1 admin login princess solo starwars
+passw0rd hello charlie donald freedom whatever qazwsx batman access flower
+hottie loveme zaq1zaq1 superman hunter secret summer winter spring autumn
+changeme default guest root test pass love god money computer internet
+cheese pepper ginger silver golden diamond purple orange yellow banana apple
+soccer hockey killer george jordan harley ranger thomas robert daniel
+matthew andrew joshua jennifer jessica amanda nicole michelle taylor
+maggie buster tigger cookie chelsea angel anthony hannah hunter2 pokemon
+the and for are but not you all any can her was one our out day get has
+him his how man new now old see two way who boy did its let put say she
+too use dad mom about after again also back because before being below
+between both call came come could every first found from give good great
+have here home house just know large last left life like line little long
+look made make many more most much must name never next night number only
+open other over part people place point right same school should show small
+some sound spell still story study such take tell than that their them then
+there these thing think this three time under very want water well went
+were what where which while white will with word work world would write
+year your correct horse battery staple dog cat fish bird lion tiger bear
+wolf eagle shark dolphin rabbit turtle family friend happy lucky smile
+sweet heart baby angel dream magic power super star moon sun sky blue red
+green black white pink rock music party beach ocean river mountain forest
+city country london paris tokyo berlin america china japan india
+company office account bank card mail email user username google facebook
+apple microsoft amazon github twitter yahoo linux windows
+`
+
+var l33tTables = []map[rune]rune{
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'l', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+}
+
+func dictionaryMatches(runes []rune) []strengthMatch {
+	var matches []strengthMatch
+	lower := toLower(runes)
+	for i := 0; i < len(lower); i++ {
+		for j := i + 2; j < len(lower); j++ {
+			word := string(lower[i : j+1])
+			variations := upperVariations(runes[i : j+1])
+			if rank, ok := commonWords[word]; ok {
+				matches = append(matches, strengthMatch{i, j, float64(rank) * variations,
+					fmt.Sprintf("common word %q", string(runes[i:j+1]))})
+			}
+			if rank, ok := commonWords[reverse(word)]; ok && len(word) > 3 {
+				matches = append(matches, strengthMatch{i, j, float64(rank) * variations * 2,
+					fmt.Sprintf("reversed common word %q", string(runes[i:j+1]))})
+			}
+			for _, table := range l33tTables {
+				subs := 0
+				unleet := make([]rune, 0, j-i+1)
+				for _, c := range lower[i : j+1] {
+					if u, ok := table[c]; ok {
+						c = u
+						subs++
+					}
+					unleet = append(unleet, c)
+				}
+				if subs == 0 {
+					continue
+				}
+				if rank, ok := commonWords[string(unleet)]; ok {
+					matches = append(matches, strengthMatch{i, j, float64(rank) * variations * math.Pow(2, float64(subs)),
+						fmt.Sprintf("common word %q with predictable substitutions", string(runes[i:j+1]))})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// upperVariations is how many case variations of a word an attacker
+// tries before finding this one.
+func upperVariations(runes []rune) float64 {
+	upper, lower := 0, 0
+	for _, c := range runes {
+		if unicode.IsUpper(c) {
+			upper++
+		} else if unicode.IsLower(c) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1]))) {
+		return 2
+	}
+	if lower < upper {
+		upper = lower
+	}
+	return math.Pow(2, float64(upper))
+}
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"qazwsxedcrfvtgbyhnujmik,ol.p;/",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik,9ol.0p;/",
+	"789456123",
+}
+
+func spatialMatches(runes []rune) []strengthMatch {
+	var matches []strengthMatch
+	lr := toLower(runes)
+	for i := 0; i < len(lr); i++ {
+		for j := i + 2; j < len(lr); j++ {
+			run := string(lr[i : j+1])
+			for _, row := range keyboardRows {
+				if strings.Contains(row, run) || strings.Contains(reverse(row), run) {
+					guesses := 47 * 4 * float64(j-i+1) * upperVariations(runes[i:j+1])
+					matches = append(matches, strengthMatch{i, j, guesses,
+						fmt.Sprintf("keyboard pattern %q", string(runes[i:j+1]))})
+					break
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func repeatMatches(runes []rune, units map[string]float64) []strengthMatch {
+	var matches []strengthMatch
+	for i := 0; i < len(runes); i++ {
+		for size := 1; i+2*size <= len(runes); size++ {
+			unit := runes[i : i+size]
+			count := 1
+			for k := i + size; k+size <= len(runes) && string(runes[k:k+size]) == string(unit); k += size {
+				count++
+			}
+			if count < 2 || (size == 1 && count < 3) {
+				continue
+			}
+			base := 12.0
+			if size > 1 {
+				var ok bool
+				if base, ok = units[string(unit)]; !ok {
+					base = estimateGuesses(unit, units).Guesses
+					units[string(unit)] = base
+				}
+			}
+			matches = append(matches, strengthMatch{i, i + size*count - 1, base * float64(count),
+				fmt.Sprintf("repeated %q", string(runes[i:i+size*count]))})
+		}
+	}
+	return matches
+}
+
+func sequenceMatches(runes []rune) []strengthMatch {
+	var matches []strengthMatch
+	for i := 0; i+2 < len(runes); i++ {
+		delta := runes[i+1] - runes[i]
+		if delta != 1 && delta != -1 {
+			continue
+		}
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta && sameClass(runes[j+1], runes[i]) {
+			j++
+		}
+		if j-i < 2 || !sameClass(runes[i+1], runes[i]) {
+			continue
+		}
+		base := 26.0
+		switch {
+		case runes[i] == 'a' || runes[i] == 'A' || runes[i] == '1' || runes[i] == '0':
+			base = 4
+		case unicode.IsDigit(runes[i]):
+			base = 10
+		}
+		if delta < 0 {
+			base *= 2
+		}
+		matches = append(matches, strengthMatch{i, j, base * float64(j-i+1),
+			fmt.Sprintf("sequence %q", string(runes[i:j+1]))})
+	}
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	return (unicode.IsDigit(a) && unicode.IsDigit(b)) ||
+		(unicode.IsLower(a) && unicode.IsLower(b)) ||
+		(unicode.IsUpper(a) && unicode.IsUpper(b))
+}
+
+var datePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(\d{1,4})[-/._ ](\d{1,2})[-/._ ](\d{1,4})$`),
+	regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`),
+	regexp.MustCompile(`^(\d{2})(\d{2})(\d{4})$`),
+	regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})$`),
+	regexp.MustCompile(`^(19\d\d|20\d\d)$`),
+}
+
+func dateMatches(runes []rune) []strengthMatch {
+	var matches []strengthMatch
+	for i := 0; i < len(runes); i++ {
+		for j := i + 3; j < len(runes) && j-i < 10; j++ {
+			s := string(runes[i : j+1])
+			for k, re := range datePatterns {
+				parts := re.FindStringSubmatch(s)
+				if parts == nil || (len(parts) > 2 && !plausibleDate(parts[1:])) {
+					continue
+				}
+				guesses := 365.0 * 120
+				switch {
+				case k == 0:
+					guesses *= 4 // Separators
+				case k == len(datePatterns)-1:
+					guesses = 120
+				}
+				matches = append(matches, strengthMatch{i, j, guesses, fmt.Sprintf("date %q", s)})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// plausibleDate tells if two of the parts can be a month and a day.
+func plausibleDate(parts []string) bool {
+	nums := make([]int, len(parts))
+	for i, part := range parts {
+		fmt.Sscan(part, &nums[i])
+	}
+	for m := range nums {
+		for d := range nums {
+			if m != d && nums[m] >= 1 && nums[m] <= 12 && nums[d] >= 1 && nums[d] <= 31 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toLower lowercases rune by rune, keeping positions as they are
+func toLower(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, c := range runes {
+		lower[i] = unicode.ToLower(c)
+	}
+	return lower
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
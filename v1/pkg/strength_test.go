@@ -0,0 +1,48 @@
+package baccounts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateStrength(t *testing.T) {
+	cases := []struct {
+		pass     string
+		maxScore int
+		minScore int
+	}{
+		{"password", 0, 0},
+		{"P@ssw0rd", 1, 0},
+		{"qwerty123", 1, 0},
+		{"aaaaaaaaaa", 1, 0},
+		{"abcdefghij", 1, 0},
+		{"19871225", 1, 0},
+		{"12/25/1987", 2, 0},
+		{"monkeymonkey", 1, 0},
+		{"drowssap", 1, 0},
+		{"correcthorsebatterystaple", 4, 3},
+		{"Hq8Zn4Wc1Rv6Ty3B", 4, 4},
+		{strings.Repeat("a", 200), 1, 0},
+		{strings.Repeat("ab1", 40), 1, 0},
+	}
+	for _, c := range cases {
+		s := EstimateStrength(c.pass)
+		if s.Score > c.maxScore || s.Score < c.minScore {
+			t.Errorf("%s: unexpected score %s, %v", c.pass, s, s.Reasons)
+		}
+	}
+
+	s := EstimateStrength("P@ssw0rd")
+	if len(s.Reasons) == 0 {
+		t.Error("Weak password without reasons")
+	}
+}
+
+func TestEstimateStrengthCap(t *testing.T) {
+	// Past the cap, nothing is scored, whatever it is
+	pass := strings.Repeat("Hq8Zn4Wc", maxStrengthRunes/8)
+	capped := EstimateStrength(pass)
+	if s := EstimateStrength(pass + "-and-9q!Xk3"); s.Guesses != capped.Guesses {
+		t.Error("Scored past the cap:", s.Guesses, capped.Guesses)
+	}
+}
@@ -0,0 +1,129 @@
+package baccounts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AddTags tags the site, ignoring tags it already has.
+func (site *Site) AddTags(tags ...string) {
+	for _, tag := range tags {
+		if tag != "" && !site.HasTag(tag) {
+			site.Tags = append(site.Tags, tag)
+		}
+	}
+	sort.Strings(site.Tags)
+	site.Touch()
+}
+
+func (site *Site) RemoveTags(tags ...string) {
+	kept := site.Tags[:0]
+	for _, t := range site.Tags {
+		if !contains(tags, t) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	site.Tags = kept
+	site.Touch()
+}
+
+func (site *Site) HasTag(tag string) bool {
+	return contains(site.Tags, tag)
+}
+
+// SetFolder moves the site to a folder like "finance/banks"; "" is the top.
+func (site *Site) SetFolder(folder string) {
+	parts := strings.FieldsFunc(folder, func(c rune) bool { return c == '/' })
+	site.Folder = strings.Join(parts, "/")
+	site.Touch()
+}
+
+// InFolder tells if the site is in the folder or one below it.
+func (site *Site) InFolder(folder string) bool {
+	folder = strings.Trim(folder, "/")
+	return folder == "" || site.Folder == folder || strings.HasPrefix(site.Folder, folder+"/")
+}
+
+// TagLabel is the tags of the site as listed, empty if it has none.
+func (site *Site) TagLabel() string {
+	if len(site.Tags) == 0 {
+		return ""
+	}
+	return "\t#" + strings.Join(site.Tags, " #")
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+type ListOptions struct {
+	Tag    string // Only sites with this tag, if set
+	Folder string // Only sites in this folder or below, if set
+	Tree   bool   // Group by folder and profile
+}
+
+func (opts ListOptions) match(site *Site) bool {
+	return (opts.Tag == "" || site.HasTag(opts.Tag)) && site.InFolder(opts.Folder)
+}
+
+// listTree prints sites grouped by folder, then by profile.
+func (b *Baccount) listTree(opts ListOptions) {
+	tree := make(map[string]map[string][]string)
+	for _, p := range b.Profiles {
+		for key, site := range p.Sites {
+			if !opts.match(site) {
+				continue
+			}
+			if tree[site.Folder] == nil {
+				tree[site.Folder] = make(map[string][]string)
+			}
+			tree[site.Folder][p.Name] = append(tree[site.Folder][p.Name], key)
+		}
+	}
+
+	folders := make([]string, 0, len(tree))
+	for folder := range tree {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	var previous []string
+	for _, folder := range folders {
+		var parts []string
+		if folder != "" {
+			parts = strings.Split(folder, "/")
+		}
+		// Print only the part of the path that differs from the previous one
+		same := 0
+		for same < len(parts) && same < len(previous) && parts[same] == previous[same] {
+			same++
+		}
+		for depth := same; depth < len(parts); depth++ {
+			fmt.Printf("%s%s/\n", strings.Repeat("  ", depth), parts[depth])
+		}
+		previous = parts
+
+		indent := strings.Repeat("  ", len(parts))
+		for _, p := range b.Profiles {
+			keys := tree[folder][p.Name]
+			if len(keys) == 0 {
+				continue
+			}
+			sort.Strings(keys)
+			fmt.Printf("%s[%s]\n", indent, p.Name)
+			for _, key := range keys {
+				site := p.Sites[key]
+				fmt.Printf("%s  %s\t%s\t%s%s\n", indent, key, site.Url, site.Mail, site.TagLabel())
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package baccounts
+
+import (
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	site := &Site{Url: "https://bank.example.com"}
+	site.AddTags("work", "finance", "work")
+	if len(site.Tags) != 2 || site.Tags[0] != "finance" {
+		t.Error("Unexpected tags", site.Tags)
+	}
+	site.RemoveTags("finance", "nosuch")
+	if !site.HasTag("work") || site.HasTag("finance") {
+		t.Error("Unexpected tags", site.Tags)
+	}
+	site.RemoveTags("work")
+	if site.Tags != nil {
+		t.Error("No tags should be nil", site.Tags)
+	}
+
+	site.SetFolder("/finance//banks/")
+	if site.Folder != "finance/banks" {
+		t.Error("Unexpected folder", site.Folder)
+	}
+	for folder, expected := range map[string]bool{
+		"":              true,
+		"finance":       true,
+		"finance/banks": true,
+		"fin":           false,
+		"finance/cards": false,
+	} {
+		if site.InFolder(folder) != expected {
+			t.Error("Unexpected InFolder", folder)
+		}
+	}
+
+	opts := ListOptions{Tag: "work", Folder: "finance"}
+	if opts.match(site) {
+		t.Error("Site without the tag matched")
+	}
+	site.AddTags("work")
+	if !opts.match(site) {
+		t.Error("Site with the tag in the folder not matched")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package baccounts
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// foreground tells if the process may use the terminal fd, rather than
+// being stopped by SIGTTIN for it as a background job.
+func foreground(fd int) bool {
+	pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	return err != nil || pgrp == syscall.Getpgrp()
+}
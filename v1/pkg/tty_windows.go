@@ -0,0 +1,9 @@
+//go:build windows
+
+package baccounts
+
+// foreground is always true, as Windows has no background jobs stopped
+// for reading the console.
+func foreground(fd int) bool {
+	return true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type renderCmd struct {
+	name string
+	out  string
+}
+
+func (*renderCmd) Name() string {
+	return "render"
+}
+func (*renderCmd) Synopsis() string {
+	return "render a template with secrets into a file only you can read"
+}
+func (*renderCmd) Usage() string {
+	return `render [-name name] -o file template
+  The template is a Go text/template with secrets of the profile:
+    {{ secret "db.internal" }}        the password of a site
+    {{ field "aws" "access_key" }}    a field of a site
+    {{ username "db.internal" }}      the name, or else mail, of a site
+  The output is written with permissions 0600.
+`
+}
+func (r *renderCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&r.name, "name", "", "Profile name")
+	f.StringVar(&r.out, "o", "", "File to write (required)")
+}
+func (r *renderCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	if f.NArg() != 1 || r.out == "" {
+		fmt.Println(r.Usage())
+		return subcommands.ExitUsageError
+	}
+	text, err := os.ReadFile(f.Arg(0))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	p, err := b.GetProfile(r.name)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	coder := baccounts.NewCoder()
+	coder.SetPassphrase()
+	out, err := p.Render(coder, filepath.Base(f.Arg(0)), string(text))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if err := baccounts.WriteFileAtomic(r.out, out, 0600); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Rendered", r.out)
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type searchCmd struct {
+	notes bool
+	pick  bool
+	limit int
+}
+
+func (*searchCmd) Name() string {
+	return "search"
+}
+func (*searchCmd) Synopsis() string {
+	return "fuzzy search sites of all profiles"
+}
+func (*searchCmd) Usage() string {
+	return `search [-notes] [-pick] [-limit n] words...
+  Every word must match the domain, URL, name, mail, tags or folder of a
+  site, or its note with -notes. The best matches come first.
+`
+}
+func (s *searchCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&s.notes, "notes", false, "Search notes too (asks for the passphrase)")
+	f.BoolVar(&s.pick, "pick", false, "Pick one of the results and copy its password like show")
+	f.IntVar(&s.limit, "limit", 20, "Show at most this many results (0 for all)")
+}
+func (s *searchCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	query := strings.Join(f.Args(), " ")
+	if query == "" {
+		fmt.Println("Nothing to search for")
+		return subcommands.ExitUsageError
+	}
+
+	var notes func(*baccounts.Site) string
+	if s.notes {
+		coder := baccounts.NewCoder()
+		coder.SetPassphrase()
+		notes = func(site *baccounts.Site) string {
+			note, err := site.Note(coder)
+			if err != nil {
+				return ""
+			}
+			return note
+		}
+	}
+
+	results := b.Search(query, notes)
+	if len(results) == 0 {
+		fmt.Println("No match:", query)
+		return subcommands.ExitFailure
+	}
+	if s.limit > 0 && len(results) > s.limit {
+		results = results[:s.limit]
+	}
+	for i, r := range results {
+		fmt.Printf("%d\t%s @ %s\t%s\t%s%s\n", i, r.Key, r.Profile.Name, r.Site.Url, r.Site.Mail, r.Site.TagLabel())
+	}
+	if !s.pick {
+		return subcommands.ExitSuccess
+	}
+
+	for {
+		line, err := readLine("Which one? ")
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		if i, err := strconv.Atoi(line); err == nil && i >= 0 && i < len(results) {
+			return b.Show(results[i].Site)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type serveCmd struct {
+	socket string
+}
+
+func (*serveCmd) Name() string {
+	return "serve"
+}
+func (*serveCmd) Synopsis() string {
+	return "serve a JSON API for tools on a Unix socket"
+}
+func (*serveCmd) Usage() string {
+	return `serve [-socket path]
+  Serves list, search, secret, generate and update as JSON over HTTP on a
+  socket only you can access, logging every request to stderr:
+    curl --unix-socket $XDG_RUNTIME_DIR/baccounts.sock http://localhost/v1/sites
+  With "Tokens": {"client name": "token"} in baccounts-config.json, clients
+  also have to send "Authorization: Bearer token".
+`
+}
+func (s *serveCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.socket, "socket", baccounts.ServeSocket(), "Socket to listen on")
+}
+func (s *serveCmd) Execute(ctx context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	// Other commands have to be able to save meanwhile
+	b.Unlock()
+	baccounts.ChooseSite = nil
+
+	config, err := baccounts.LoadConfig()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	coder, err := b.Coder()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if err := coder.SetCheckedPassphrase(); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	ln, err := baccounts.ListenSocket(s.socket)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	server := &http.Server{Handler: baccounts.NewServer(b, datafile, coder, config.Tokens)}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		server.Shutdown(ctx)
+	}()
+
+	fmt.Printf("Serving on %s, %d client tokens\n", s.socket, len(config.Tokens))
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println("Stopped")
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+type sshKeyCmd struct {
+	site    string
+	name    string
+	keyfile string
+	delete  bool
+}
+
+func (*sshKeyCmd) Name() string {
+	return "ssh-key"
+}
+func (*sshKeyCmd) Synopsis() string {
+	return "import, show or delete the SSH key of the site"
+}
+func (*sshKeyCmd) Usage() string {
+	return `ssh-key -site site [-name name] [-import ~/.ssh/id_ed25519 | -delete]
+  Prints the public key of the site, after importing its private key if
+  -import is given. A key with a passphrase is kept without it, encrypted
+  like passwords; 'baccounts ssh-agent' serves it.
+`
+}
+func (s *sshKeyCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.site, "site", "", "Site of the key (required)")
+	f.StringVar(&s.name, "name", "", "Profile name")
+	f.StringVar(&s.keyfile, "import", "", "Private key file to import")
+	f.BoolVar(&s.delete, "delete", false, "Delete the key of the site")
+}
+func (s *sshKeyCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	p, site, err := findSite(b, s.name, s.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	switch {
+	case s.delete:
+		if site.SSHKey == nil {
+			fmt.Println("No SSH key for", site.Url)
+			return subcommands.ExitFailure
+		}
+		site.SSHKey = nil
+		site.Touch()
+		b.SetMessage("Delete SSH key of %s @ %s", s.site, p.Name)
+	case s.keyfile != "":
+		pemBytes, err := os.ReadFile(s.keyfile)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		coder, err := b.Coder()
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		err = site.SetSSHKey(coder, pemBytes, nil, filepath.Base(s.keyfile))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			var pass string
+			if pass, err = baccounts.ReadPassword("Passphrase of " + s.keyfile + ":"); err == nil {
+				err = site.SetSSHKey(coder, pemBytes, []byte(pass), filepath.Base(s.keyfile))
+			}
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		b.SetMessage("Import SSH key of %s @ %s", s.site, p.Name)
+	default:
+		if site.SSHKey == nil {
+			fmt.Println("No SSH key for", site.Url)
+			return subcommands.ExitFailure
+		}
+		fmt.Println(site.SSHKey.PublicKey)
+		return subcommands.ExitSuccess
+	}
+
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	if site.SSHKey != nil {
+		fmt.Println(site.SSHKey.PublicKey)
+	}
+	return subcommands.ExitSuccess
+}
+
+type sshAgentCmd struct {
+	socket   string
+	lifetime time.Duration
+	confirm  bool
+}
+
+func (*sshAgentCmd) Name() string {
+	return "ssh-agent"
+}
+func (*sshAgentCmd) Synopsis() string {
+	return "serve SSH keys of sites to ssh as an agent"
+}
+func (*sshAgentCmd) Usage() string {
+	return `ssh-agent [-socket path] [-lifetime 0] [-confirm=true]
+  Serves the SSH keys of all sites with the agent protocol until stopped,
+  like 'baccounts agent'. A key is decrypted only to sign, after the use
+  is confirmed, and is forgotten after -lifetime. Keys can't be added
+  with ssh-add: use ssh-key.
+  Run in a terminal of its own, it asks there for the confirmation and
+  the passphrase. In the background it can't: give a "Confirm" command in
+  baccounts-config.json, or -confirm=false, and start 'baccounts agent'
+  first; otherwise uses are denied. E.g.
+    baccounts agent -ttl 8h
+    baccounts ssh-agent 2>/dev/null &
+    export SSH_AUTH_SOCK=` + baccounts.SSHAgentSocket() + `
+`
+}
+func (s *sshAgentCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.socket, "socket", baccounts.SSHAgentSocket(), "Socket to listen on")
+	f.DurationVar(&s.lifetime, "lifetime", 0, "How long to keep a decrypted key; only for one use if 0")
+	f.BoolVar(&s.confirm, "confirm", true, "Confirm every use of a key")
+}
+func (s *sshAgentCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+
+	// Only SSH_AUTH_SOCK goes to stdout, for the shell to read
+	out := protocolStdout()
+	// Other commands have to be able to save meanwhile
+	b.Unlock()
+	baccounts.ChooseSite = nil
+
+	config, err := baccounts.LoadConfig()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	a := baccounts.NewSSHAgent(b)
+	a.Lifetime = s.lifetime
+	if s.confirm {
+		a.Confirm = confirmer(config.Confirm)
+		if len(config.Confirm) == 0 {
+			if tty, err := baccounts.OpenTTY(); err != nil {
+				fmt.Printf("Warning: every use will be denied without a Confirm command (%v)\n", err)
+			} else {
+				tty.Close()
+			}
+		}
+	}
+	a.NewCoder = func() (*baccounts.Coder, error) {
+		coder := baccounts.NewCoder()
+		return coder, unlockUnattended(coder)
+	}
+
+	ln, err := baccounts.ListenSocket(s.socket)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-signals
+		ln.Close()
+	}()
+
+	fmt.Printf("SSH agent listening on %s\n", s.socket)
+	fmt.Fprintf(out, "SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", shellQuote(s.socket))
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			break
+		} else if err != nil {
+			fmt.Println("Error:", err)
+			return subcommands.ExitFailure
+		}
+		go func() {
+			defer conn.Close()
+			agent.ServeAgent(a, conn)
+		}()
+	}
+	fmt.Println("SSH agent stopped")
+	return subcommands.ExitSuccess
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
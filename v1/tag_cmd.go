@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+)
+
+type tagCmd struct {
+	site   string
+	name   string
+	add    string
+	remove string
+}
+
+func (*tagCmd) Name() string {
+	return "tag"
+}
+func (*tagCmd) Synopsis() string {
+	return "add or remove tags of the site"
+}
+func (*tagCmd) Usage() string {
+	return `tag -site site [-name name] [-add tag,tag...] [-remove tag,tag...]
+`
+}
+func (t *tagCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&t.site, "site", "", "Site to tag (required)")
+	f.StringVar(&t.name, "name", "", "Profile name")
+	f.StringVar(&t.add, "add", "", "Comma-separated tags to add")
+	f.StringVar(&t.remove, "remove", "", "Comma-separated tags to remove")
+}
+func (t *tagCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	p, site, err := findSite(b, t.name, t.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	if t.add == "" && t.remove == "" {
+		fmt.Println(strings.Join(site.Tags, " "))
+		return subcommands.ExitSuccess
+	}
+
+	if t.add != "" {
+		site.AddTags(strings.Split(t.add, ",")...)
+	}
+	if t.remove != "" {
+		site.RemoveTags(strings.Split(t.remove, ",")...)
+	}
+	b.SetMessage("Tag %s @ %s: %s", t.site, p.Name, strings.Join(site.Tags, " "))
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println(strings.Join(site.Tags, " "))
+	return subcommands.ExitSuccess
+}
+
+type folderCmd struct {
+	site   string
+	name   string
+	folder string
+}
+
+func (*folderCmd) Name() string {
+	return "folder"
+}
+func (*folderCmd) Synopsis() string {
+	return "move the site to a folder"
+}
+func (*folderCmd) Usage() string {
+	return `folder -site site [-name name] -set finance/banks
+  An empty folder moves the site back to the top.
+`
+}
+func (c *folderCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.site, "site", "", "Site to move (required)")
+	f.StringVar(&c.name, "name", "", "Profile name")
+	f.StringVar(&c.folder, "set", "", "Slash-separated folder")
+}
+func (c *folderCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	p, site, err := findSite(b, c.name, c.site)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	site.SetFolder(c.folder)
+	b.SetMessage("Move %s @ %s to folder %s", c.site, p.Name, site.Folder)
+	if err := b.UpdateConfigFile(datafile); err != nil {
+		fmt.Println("Failed to save", datafile, err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/atotto/clipboard"
+	"github.com/google/subcommands"
+	baccounts "github.com/kuenishi/baccounts/pkg"
+	"golang.org/x/term"
+)
+
+type uiCmd struct {
+	query string
+}
+
+func (*uiCmd) Name() string {
+	return "ui"
+}
+func (*uiCmd) Synopsis() string {
+	return "pick sites of all profiles in a full-screen terminal UI"
+}
+func (*uiCmd) Usage() string {
+	return `ui [-query words]
+  Type to filter, up/down (or ^P/^N) to move, then:
+    enter  copy password      ^U  copy username
+    ^O     copy OTP code      ^R  rotate password
+    ^D     delete site        tab show/hide details
+    esc    quit
+`
+}
+func (u *uiCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&u.query, "query", "", "Filter to start with")
+}
+func (u *uiCmd) Execute(_ context.Context, f *flag.FlagSet, argv ...interface{}) subcommands.ExitStatus {
+	var b = (argv[0]).(*baccounts.Baccount)
+	var datafile = (argv[1]).(string)
+
+	// Other commands have to be able to save meanwhile
+	b.Unlock()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		fmt.Println("ui needs a terminal")
+		return subcommands.ExitFailure
+	}
+	// Before the screen is taken, as NewCoder prints the keyrings
+	coder, err := b.Coder()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+
+	p := &picker{b: b, datafile: datafile, coder: coder, fd: fd,
+		in: bufio.NewReader(os.Stdin), query: u.query}
+	if err := p.run(); err != nil {
+		fmt.Println("Error:", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// Keys other than runes typed into the filter
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyEsc
+)
+
+const (
+	keyEnter     = '\r'
+	keyTab       = '\t'
+	keyBackspace = 0x7f
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlH     = 0x08
+	keyCtrlN     = 0x0e
+	keyCtrlO     = 0x0f
+	keyCtrlP     = 0x10
+	keyCtrlR     = 0x12
+	keyCtrlU     = 0x15
+)
+
+type picker struct {
+	b        *baccounts.Baccount
+	datafile string
+	coder    *baccounts.Coder
+	unlocked bool // The passphrase of coder worked once
+
+	fd    int
+	state *term.State
+	in    *bufio.Reader
+
+	query    string
+	results  []baccounts.SearchResult
+	selected int
+	top      int // First result on screen
+	details  bool
+	status   string
+}
+
+func (p *picker) run() error {
+	if err := p.enter(); err != nil {
+		return err
+	}
+	defer p.leave()
+
+	p.filter()
+	for {
+		p.draw()
+		key, err := p.readKey()
+		if err != nil {
+			return err
+		}
+		p.status = ""
+
+		switch key {
+		case keyEsc, keyCtrlC:
+			return nil
+		case keyUp, keyCtrlP:
+			p.move(-1)
+		case keyDown, keyCtrlN:
+			p.move(1)
+		case keyTab:
+			p.details = !p.details
+		case keyBackspace, keyCtrlH:
+			if q := []rune(p.query); len(q) > 0 {
+				p.query = string(q[:len(q)-1])
+				p.filter()
+			}
+		case keyEnter:
+			p.act(p.copyPass)
+		case keyCtrlU:
+			p.act(p.copyUser)
+		case keyCtrlO:
+			p.act(p.copyOTP)
+		case keyCtrlR:
+			p.act(p.rotate)
+		case keyCtrlD:
+			p.act(p.delete)
+		default:
+			if key > 0 && unicode.IsPrint(key) {
+				p.query += string(key)
+				p.filter()
+			}
+		}
+	}
+}
+
+// enter takes the terminal: raw mode on the alternate screen.
+func (p *picker) enter() error {
+	state, err := term.MakeRaw(p.fd)
+	if err != nil {
+		return err
+	}
+	p.state = state
+	fmt.Print("\x1b[?1049h")
+	return nil
+}
+
+// leave gives the terminal back as it was.
+func (p *picker) leave() {
+	fmt.Print("\x1b[?1049l")
+	term.Restore(p.fd, p.state)
+}
+
+func (p *picker) filter() {
+	p.results = p.b.Search(p.query, nil)
+	p.selected, p.top = 0, 0
+}
+
+func (p *picker) move(delta int) {
+	p.selected += delta
+	if p.selected >= len(p.results) {
+		p.selected = len(p.results) - 1
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
+
+func (p *picker) current() *baccounts.SearchResult {
+	if p.selected < len(p.results) {
+		return &p.results[p.selected]
+	}
+	return nil
+}
+
+// act runs an action on the selected site, with its outcome on the
+// status line.
+func (p *picker) act(action func(r *baccounts.SearchResult) (string, error)) {
+	r := p.current()
+	if r == nil {
+		p.status = "Nothing selected"
+		return
+	}
+	status, err := action(r)
+	if err != nil {
+		p.status = "Error: " + err.Error()
+		return
+	}
+	p.status = status
+}
+
+// unlock asks the passphrase once, on the normal screen, before the
+// first action that decrypts.
+func (p *picker) unlock() {
+	if p.unlocked {
+		return
+	}
+	p.leave()
+	p.coder.SetPassphrase()
+	p.enter()
+}
+
+// decrypting runs f with the passphrase set, asking again next time if
+// it didn't work.
+func (p *picker) decrypting(f func() error) error {
+	p.unlock()
+	if err := f(); err != nil {
+		p.unlocked = false
+		return err
+	}
+	p.unlocked = true
+	return nil
+}
+
+func (p *picker) copyPass(r *baccounts.SearchResult) (string, error) {
+	err := p.decrypting(func() error { return p.b.CopyPass(p.coder, r.Site) })
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Pass for %s (%s) copied to clipboard", r.Site.Url, r.Site.Name), nil
+}
+
+func (p *picker) copyUser(r *baccounts.SearchResult) (string, error) {
+	user := r.Site.Username()
+	if err := clipboard.WriteAll(user); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Username %s copied to clipboard", user), nil
+}
+
+func (p *picker) copyOTP(r *baccounts.SearchResult) (string, error) {
+	site := r.Site
+	if site.OTP == nil {
+		return "", fmt.Errorf("No OTP for %s: set one with otp-set", site.Url)
+	}
+	var code string
+	err := p.decrypting(func() (err error) {
+		code, err = site.OTPCode(p.coder, time.Now())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := clipboard.WriteAll(code); err != nil {
+		return "", err
+	}
+	if site.OTP.Type == "hotp" {
+		p.b.SetMessage("Advance HOTP counter for %s @ %s", r.Key, r.Profile.Name)
+		if err := p.save(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Code for %s copied to clipboard (counter %d)", site.Url, site.OTP.Counter-1), nil
+	}
+	return fmt.Sprintf("Code for %s copied to clipboard, valid for %v", site.Url, site.OTP.Remaining(time.Now())), nil
+}
+
+// rotatedField keeps a rotated password until it's confirmed to be set
+// on the site, as the old one is the one that works until then.
+const rotatedField = "new-password"
+
+func (p *picker) rotate(r *baccounts.SearchResult) (string, error) {
+	if !p.confirm(fmt.Sprintf("Rotate password of %s @ %s?", r.Key, r.Profile.Name)) {
+		return "Not rotated", nil
+	}
+	pass, err := baccounts.GeneratePassword(baccounts.DefaultPassLength, false)
+	if err != nil {
+		return "", err
+	}
+	site := r.Site
+	old := snapshot(site)
+	if err := site.SetField(p.coder, rotatedField, pass, true); err != nil {
+		return "", err
+	}
+	p.b.SetMessage("Generate new password for %s @ %s", r.Key, r.Profile.Name)
+	if err := p.save(); err != nil {
+		*site = old
+		return "", err
+	}
+	// Only a saved password is to be set on the site
+	if err := clipboard.WriteAll(pass); err != nil {
+		return "", err
+	}
+	if !p.confirm(fmt.Sprintf("New pass for %s copied to clipboard: changed it on the site?", site.Url)) {
+		return fmt.Sprintf("Kept the old password of %s, the new one is in field %s", site.Url, rotatedField), nil
+	}
+
+	old = snapshot(site)
+	if err := site.SetPassword(p.coder, pass); err != nil {
+		return "", err
+	}
+	site.DeleteField(rotatedField)
+	p.b.SetMessage("Rotate password for %s @ %s", r.Key, r.Profile.Name)
+	if err := p.save(); err != nil {
+		*site = old
+		return "", err
+	}
+	return fmt.Sprintf("Password of %s rotated", site.Url), nil
+}
+
+// snapshot copies site, to put it back if saving a change fails.
+func snapshot(site *baccounts.Site) baccounts.Site {
+	old := *site
+	if site.Fields != nil {
+		old.Fields = make(map[string]*baccounts.Field, len(site.Fields))
+		for name, field := range site.Fields {
+			old.Fields[name] = field
+		}
+	}
+	return old
+}
+
+func (p *picker) delete(r *baccounts.SearchResult) (string, error) {
+	if !p.confirm(fmt.Sprintf("Delete %s @ %s?", r.Key, r.Profile.Name)) {
+		return "Not deleted", nil
+	}
+	if err := r.Profile.DeleteSite(r.Key); err != nil {
+		return "", err
+	}
+	p.b.SetMessage("Delete %s @ %s", r.Key, r.Profile.Name)
+	if err := p.save(); err != nil {
+		r.Profile.Sites[r.Key] = r.Site
+		return "", err
+	}
+	status := fmt.Sprintf("Deleted %s @ %s", r.Key, r.Profile.Name)
+	selected := p.selected
+	p.filter()
+	p.move(selected)
+	return status, nil
+}
+
+// save writes the datafile, whose messages would garble the screen
+// unless it's given back meanwhile.
+func (p *picker) save() error {
+	p.leave()
+	defer p.enter()
+	return p.b.UpdateConfigFile(p.datafile)
+}
+
+func (p *picker) confirm(question string) bool {
+	p.status = question + " [y/N]"
+	p.draw()
+	key, err := p.readKey()
+	return err == nil && (key == 'y' || key == 'Y')
+}
+
+func (p *picker) readKey() (rune, error) {
+	c, _, err := p.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if c != 0x1b {
+		return c, nil
+	}
+	// A lone escape, or the start of an arrow key like "\x1b[A"
+	if p.in.Buffered() < 2 {
+		return keyEsc, nil
+	}
+	seq := make([]byte, 2)
+	if _, err := p.in.Read(seq); err != nil {
+		return 0, err
+	}
+	switch string(seq) {
+	case "[A", "OA":
+		return keyUp, nil
+	case "[B", "OB":
+		return keyDown, nil
+	}
+	return 0, nil
+}
+
+func (p *picker) draw() {
+	cols, rows, err := term.GetSize(p.fd)
+	if err != nil || cols <= 0 || rows <= 0 {
+		cols, rows = 80, 24
+	}
+
+	var details []string
+	if p.details {
+		details = p.detailLines()
+	}
+	// Query, status and help lines take three
+	height := rows - 3 - len(details)
+	if height < 1 {
+		height = 1
+	}
+	if p.selected < p.top {
+		p.top = p.selected
+	}
+	if p.selected >= p.top+height {
+		p.top = p.selected - height + 1
+	}
+
+	var buf strings.Builder
+	buf.WriteString("\x1b[H\x1b[2J")
+	line := func(s string) {
+		buf.WriteString(truncate(s, cols))
+		buf.WriteString("\x1b[0m\r\n")
+	}
+	prompt := fmt.Sprintf("%d/%d> %s", len(p.results), p.count(), p.query)
+	line(prompt)
+	for i := p.top; i < p.top+height; i++ {
+		if i >= len(p.results) {
+			line("")
+			continue
+		}
+		r := p.results[i]
+		s := fmt.Sprintf("  %s @ %s  %s  %s%s", r.Key, r.Profile.Name, r.Site.Url, r.Site.Mail,
+			strings.ReplaceAll(r.Site.TagLabel(), "\t", "  "))
+		if i == p.selected {
+			s = "\x1b[7m>" + s[1:]
+		}
+		line(s)
+	}
+	for _, d := range details {
+		line(d)
+	}
+	line(p.status)
+	buf.WriteString(truncate("enter pass  ^U user  ^O otp  ^R rotate  ^D delete  tab details  esc quit", cols))
+	// Back to where the query is typed
+	fmt.Fprintf(&buf, "\x1b[1;%dH", len([]rune(prompt))+1)
+	fmt.Print(buf.String())
+}
+
+func (p *picker) count() int {
+	n := 0
+	for _, profile := range p.b.Profiles {
+		n += len(profile.Sites)
+	}
+	return n
+}
+
+// detailLines is the details pane of the selected site; secrets stay
+// encrypted.
+func (p *picker) detailLines() []string {
+	r := p.current()
+	if r == nil {
+		return []string{"----"}
+	}
+	site := r.Site
+	lines := []string{
+		"----",
+		"profile:  " + r.Profile.Name,
+		"url:      " + site.Url,
+		"name:     " + site.Name,
+		"mail:     " + site.Mail,
+		"modified: " + site.Modified.Format(time.RFC3339),
+	}
+	if site.OTP != nil {
+		lines = append(lines, fmt.Sprintf("otp:      %s %s, %d digits", strings.ToUpper(site.OTP.Type), site.OTP.Algorithm, site.OTP.Digits))
+	}
+	if len(site.Tags) > 0 {
+		lines = append(lines, "tags:     "+strings.Join(site.Tags, " "))
+	}
+	if site.Folder != "" {
+		lines = append(lines, "folder:   "+site.Folder)
+	}
+	for _, name := range site.FieldNames() {
+		if field := site.Fields[name]; field.Secret {
+			lines = append(lines, fmt.Sprintf("field:    %s (secret)", name))
+		} else {
+			lines = append(lines, fmt.Sprintf("field:    %s: %s", name, field.Value))
+		}
+	}
+	if site.EncodedNote != "" {
+		lines = append(lines, "note:     yes")
+	}
+	return lines
+}
+
+// truncate cuts s to fit cols columns, not counting escape sequences.
+func truncate(s string, cols int) string {
+	var out []rune
+	n, escape := 0, false
+	for _, c := range s {
+		switch {
+		case c == 0x1b:
+			escape = true
+		case escape:
+			escape = !unicode.IsLetter(c)
+		default:
+			if n >= cols {
+				return string(out)
+			}
+			n++
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}